@@ -0,0 +1,53 @@
+// Package adler32 implements the Adler-32 checksum (RFC 1950) backed by the native zlib implementation.
+// It may be used as a replacement for hash/adler32 and additionally allows to combine checksums.
+package adler32
+
+import (
+	"hash"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+// Size is the size of an Adler-32 checksum in bytes.
+const Size = 4
+
+const initial = 1
+
+type digest uint32
+
+// New returns a new hash.Hash32 computing the Adler-32 checksum.
+// Its Sum method lays the value out in big-endian byte order.
+func New() hash.Hash32 {
+	d := digest(initial)
+	return &d
+}
+
+func (d *digest) Reset() { *d = initial }
+
+func (d *digest) Size() int { return Size }
+
+func (d *digest) BlockSize() int { return 4 }
+
+func (d *digest) Write(p []byte) (int, error) {
+	*d = digest(native.Adler32(uint32(*d), p))
+	return len(p), nil
+}
+
+func (d *digest) Sum32() uint32 { return uint32(*d) }
+
+func (d *digest) Sum(in []byte) []byte {
+	s := uint32(*d)
+	return append(in, byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+}
+
+// Checksum returns the Adler-32 checksum of data.
+func Checksum(data []byte) uint32 {
+	return native.Adler32(initial, data)
+}
+
+// Combine returns the Adler-32 checksum of two concatenated chunks of data given the checksum sum1 of the first chunk,
+// the checksum sum2 of the second chunk and its length len2. This allows to checksum chunks independently,
+// for example in parallel, and merge the checksums afterwards.
+func Combine(sum1, sum2 uint32, len2 int64) uint32 {
+	return native.Adler32Combine(sum1, sum2, len2)
+}
@@ -0,0 +1,67 @@
+package adler32
+
+import (
+	"bytes"
+	"hash/adler32"
+	"math"
+	"testing"
+)
+
+var testData = [][]byte{
+	nil,
+	[]byte("a"),
+	[]byte("hello world"),
+	bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 10000),
+}
+
+func TestChecksum(t *testing.T) {
+	for _, data := range testData {
+		if want, got := adler32.Checksum(data), Checksum(data); want != got {
+			t.Errorf("wrong checksum of %d bytes: want %#08x; got %#08x", len(data), want, got)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, data := range testData {
+		want, h := adler32.New(), New()
+		for i := 0; i < len(data); i += 1000 {
+			end := i + 1000
+			if end > len(data) {
+				end = len(data)
+			}
+			want.Write(data[i:end])
+			h.Write(data[i:end])
+		}
+		if !bytes.Equal(want.Sum([]byte{1}), h.Sum([]byte{1})) {
+			t.Errorf("wrong sum of %d bytes: want %x; got %x", len(data), want.Sum(nil), h.Sum(nil))
+		}
+		if h.Size() != Size || h.BlockSize() != want.BlockSize() {
+			t.Errorf("wrong sizes: %d, %d", h.Size(), h.BlockSize())
+		}
+
+		h.Reset()
+		if h.Sum32() != adler32.Checksum(nil) {
+			t.Errorf("wrong checksum after reset: %#08x", h.Sum32())
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	data := testData[len(testData)-1]
+	for _, split := range []int{0, 1, 4096, len(data) / 2, len(data)} {
+		a, b := data[:split], data[split:]
+		if want, got := adler32.Checksum(data), Combine(Checksum(a), Checksum(b), int64(len(b))); want != got {
+			t.Errorf("wrong combined checksum split at %d: want %#08x; got %#08x", split, want, got)
+		}
+	}
+}
+
+func TestCombine_LongLength(t *testing.T) {
+	x, y, z := Checksum([]byte("x")), Checksum([]byte("y")), Checksum([]byte("z"))
+	// lengths beyond 32 bits have to give the same result as combining in two steps
+	const length = math.MaxInt32
+	if want, got := Combine(Combine(x, y, length), z, length), Combine(x, Combine(y, z, length), 2*length); want != got {
+		t.Errorf("wrong combined checksum: want %#08x; got %#08x", want, got)
+	}
+}
@@ -0,0 +1,56 @@
+// Package crc32 implements the CRC-32 checksum with the IEEE polynomial, as used by gzip,
+// backed by the native zlib implementation.
+// It may be used as a replacement for the IEEE functions of hash/crc32 and additionally allows to combine checksums.
+package crc32
+
+import (
+	"hash"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+// Size is the size of a CRC-32 checksum in bytes.
+const Size = 4
+
+type digest uint32
+
+// NewIEEE returns a new hash.Hash32 computing the CRC-32 checksum using the IEEE polynomial.
+// Its Sum method lays the value out in big-endian byte order.
+func NewIEEE() hash.Hash32 {
+	return new(digest)
+}
+
+func (d *digest) Reset() { *d = 0 }
+
+func (d *digest) Size() int { return Size }
+
+func (d *digest) BlockSize() int { return 1 }
+
+func (d *digest) Write(p []byte) (int, error) {
+	*d = digest(native.CRC32(uint32(*d), p))
+	return len(p), nil
+}
+
+func (d *digest) Sum32() uint32 { return uint32(*d) }
+
+func (d *digest) Sum(in []byte) []byte {
+	s := uint32(*d)
+	return append(in, byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+}
+
+// ChecksumIEEE returns the CRC-32 checksum of data using the IEEE polynomial.
+func ChecksumIEEE(data []byte) uint32 {
+	return native.CRC32(0, data)
+}
+
+// Update returns the result of adding the bytes in p to the crc.
+func Update(crc uint32, p []byte) uint32 {
+	return native.CRC32(crc, p)
+}
+
+// Combine returns the CRC-32 checksum of two concatenated chunks of data given the checksum sum1 of the first chunk,
+// the checksum sum2 of the second chunk and its length len2. This allows to checksum chunks independently,
+// for example in parallel, and merge the checksums afterwards.
+func Combine(sum1, sum2 uint32, len2 int64) uint32 {
+	return native.CRC32Combine(sum1, sum2, len2)
+}
@@ -0,0 +1,74 @@
+package crc32
+
+import (
+	"bytes"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+var testData = [][]byte{
+	nil,
+	[]byte("a"),
+	[]byte("hello world"),
+	bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 10000),
+}
+
+func TestChecksum(t *testing.T) {
+	for _, data := range testData {
+		if want, got := crc32.ChecksumIEEE(data), ChecksumIEEE(data); want != got {
+			t.Errorf("wrong checksum of %d bytes: want %#08x; got %#08x", len(data), want, got)
+		}
+	}
+}
+
+func TestNewIEEE(t *testing.T) {
+	for _, data := range testData {
+		want, h := crc32.NewIEEE(), NewIEEE()
+		for i := 0; i < len(data); i += 1000 {
+			end := i + 1000
+			if end > len(data) {
+				end = len(data)
+			}
+			want.Write(data[i:end])
+			h.Write(data[i:end])
+		}
+		if !bytes.Equal(want.Sum([]byte{1}), h.Sum([]byte{1})) {
+			t.Errorf("wrong sum of %d bytes: want %x; got %x", len(data), want.Sum(nil), h.Sum(nil))
+		}
+		if h.Size() != Size || h.BlockSize() != want.BlockSize() {
+			t.Errorf("wrong sizes: %d, %d", h.Size(), h.BlockSize())
+		}
+
+		h.Reset()
+		if h.Sum32() != crc32.ChecksumIEEE(nil) {
+			t.Errorf("wrong checksum after reset: %#08x", h.Sum32())
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	data := testData[len(testData)-1]
+	for _, split := range []int{0, 1, 4096, len(data) / 2, len(data)} {
+		a, b := data[:split], data[split:]
+		if want, got := crc32.ChecksumIEEE(data), Combine(ChecksumIEEE(a), ChecksumIEEE(b), int64(len(b))); want != got {
+			t.Errorf("wrong combined checksum split at %d: want %#08x; got %#08x", split, want, got)
+		}
+	}
+}
+
+func TestCombine_LongLength(t *testing.T) {
+	x, y, z := ChecksumIEEE([]byte("x")), ChecksumIEEE([]byte("y")), ChecksumIEEE([]byte("z"))
+	// lengths beyond 32 bits have to give the same result as combining in two steps
+	const length = math.MaxInt32
+	if want, got := Combine(Combine(x, y, length), z, length), Combine(x, Combine(y, z, length), 2*length); want != got {
+		t.Errorf("wrong combined checksum: want %#08x; got %#08x", want, got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	data := testData[len(testData)-1]
+	if want, got := crc32.ChecksumIEEE(data), Update(Update(0, data[:100]), data[100:]); want != got {
+		t.Errorf("wrong checksum: want %#08x; got %#08x", want, got)
+	}
+}
@@ -12,6 +12,8 @@ const (
 	//DefaultCompression is a compromise between BestSpeed and BestCompression.
 	//The level might change if algorithms change.
 	DefaultCompression = -1
+	//StoreOnly selects NoCompression in the Level of option structs like Options, whose zero value means DefaultCompression.
+	StoreOnly = -2
 
 	// Compression Strategies
 
@@ -27,4 +29,20 @@ const (
 	Fixed = 4
 	// DefaultStrategy is the default compression strategy that should be used for most appliances
 	DefaultStrategy = 0
+
+	// Flush Modes
+
+	// PartialFlush flushes all pending output but the last few bits, which are completed by an empty
+	// fixed code block. It is what SSH-style framing expects.
+	PartialFlush = 1
+	// SyncFlush flushes all pending output and aligns it to a byte boundary with an empty stored block,
+	// so that a reader can decompress all data written so far. This is what Flush does.
+	SyncFlush = 2
+	// FullFlush flushes like SyncFlush and additionally resets the compression state,
+	// so that a reader can resume decompression from this point if previous data was corrupted.
+	// Flushing like this too often degrades compression.
+	FullFlush = 3
+	// BlockFlush completes the current deflate block without aligning the output to a byte boundary,
+	// so up to seven bits of the block may still be pending. It is meant for indexers and similar applications.
+	BlockFlush = 5
 )
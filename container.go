@@ -0,0 +1,45 @@
+package zlib
+
+// Container is the format wrapping the DEFLATE data of a stream
+type Container int
+
+const (
+	// ContainerUnknown means that the container has not been detected yet
+	ContainerUnknown Container = iota
+	// ContainerZlib is the zlib format (RFC 1950)
+	ContainerZlib
+	// ContainerGzip is the gzip format (RFC 1952)
+	ContainerGzip
+	// ContainerRaw is raw DEFLATE (RFC 1951) without any header or trailer
+	ContainerRaw
+)
+
+func (c Container) String() string {
+	switch c {
+	case ContainerZlib:
+		return "zlib"
+	case ContainerGzip:
+		return "gzip"
+	case ContainerRaw:
+		return "raw"
+	default:
+		return "unknown"
+	}
+}
+
+// detectContainer guesses the container from the first bytes of a stream.
+// Anything neither starting with the gzip magic number nor a valid zlib header is considered raw DEFLATE.
+func detectContainer(b []byte) Container {
+	if len(b) < 2 {
+		return ContainerRaw
+	}
+	if b[0] == 0x1f && b[1] == 0x8b {
+		return ContainerGzip
+	}
+
+	method, info := b[0]&0x0f, b[0]>>4
+	if method == 8 && info <= 7 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0 {
+		return ContainerZlib
+	}
+	return ContainerRaw
+}
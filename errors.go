@@ -2,11 +2,53 @@ package zlib
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/4kills/go-zlib/native"
 )
 
+// DictionaryError is returned if a stream requires a preset dictionary that was either not provided
+// or does not match the one used for compression. Its ID is the Adler-32 checksum of the expected dictionary.
+type DictionaryError = native.DictionaryError
+
 var (
-	errIsClosed        = errors.New("zlib: stream is already closed: you may not use this anymore")
-	errNoInput         = errors.New("zlib: no input provided: please provide at least 1 element")
-	errInvalidLevel    = errors.New("zlib: invalid compression level provided")
-	errInvalidStrategy = errors.New("zlib: invalid compression strategy provided")
+	// ErrData is matched (via errors.Is) by every error returned due to corrupted or otherwise invalid compressed data
+	ErrData = native.ErrData
+
+	// ErrDictionary is matched (via errors.Is) by every error returned due to a missing or invalid dictionary
+	ErrDictionary = native.ErrDictionary
+
+	// ErrMemory is matched (via errors.Is) by every error caused by zlib failing to allocate memory,
+	// either because the system is out of memory or because of the budget set by SetMemoryBudget
+	ErrMemory = native.ErrMemory
+
+	// ErrOutputLimit is returned once decompressed data would exceed Limits.MaxOutput
+	ErrOutputLimit = errors.New("zlib: decompressed data exceeds the output limit")
+
+	// ErrRatioLimit is returned once decompressed data would exceed Limits.MaxRatio
+	ErrRatioLimit = errors.New("zlib: decompressed data exceeds the compression ratio limit")
+
+	// ErrPoolExhausted is returned by a Pool without Wait once its maximum number of streams is in use
+	ErrPoolExhausted = errors.New("zlib: all streams of the pool are in use")
+
+	errIsClosed           = errors.New("zlib: stream is already closed: you may not use this anymore")
+	errNoInput            = errors.New("zlib: no input provided: please provide at least 1 element")
+	errInvalidLevel       = errors.New("zlib: invalid compression level provided")
+	errInvalidStrategy    = errors.New("zlib: invalid compression strategy provided")
+	errInvalidWindowBits  = errors.New("zlib: invalid window bits provided")
+	errInvalidMemLevel    = errors.New("zlib: invalid memory level provided")
+	errInvalidFlushMode   = errors.New("zlib: invalid flush mode provided")
+	errInvalidPrimeBits   = errors.New("zlib: invalid number of bits to prime provided")
+	errInvalidIndex       = errors.New("zlib: invalid index")
+	errInvalidWhence      = errors.New("zlib: invalid whence")
+	errNegativeOffset     = errors.New("zlib: negative offset")
+	errInvalidLimits      = errors.New("zlib: invalid limits provided")
+	errInvalidBudget      = errors.New("zlib: invalid memory budget provided")
+	errInvalidPoolOptions = errors.New("zlib: invalid pool options provided")
+	errPoolClosed         = errors.New("zlib: pool is already closed")
+	errNotPooled          = errors.New("zlib: writer or reader does not belong to the pool")
+	errInvalidConcurrency = errors.New("zlib: invalid concurrency provided")
+	errInvalidBlockSize   = errors.New("zlib: invalid block size provided")
+	errHeader             = fmt.Errorf("zlib: invalid header: %w", native.ErrData)
+	errChecksum           = fmt.Errorf("zlib: invalid checksum: %w", native.ErrData)
 )
@@ -0,0 +1,40 @@
+package gzip
+
+import "github.com/4kills/go-zlib"
+
+const (
+	// Compression Levels
+
+	//NoCompression does not compress given input
+	NoCompression = zlib.NoCompression
+	//BestSpeed is fastest but with lowest compression
+	BestSpeed = zlib.BestSpeed
+	//BestCompression is slowest but with best compression
+	BestCompression = zlib.BestCompression
+	//DefaultCompression is a compromise between BestSpeed and BestCompression.
+	//The level might change if algorithms change.
+	DefaultCompression = zlib.DefaultCompression
+
+	// Compression Strategies
+
+	// Filtered is more effective for small (but not all too many) randomly distributed values.
+	Filtered = zlib.Filtered
+	//HuffmanOnly only uses Huffman encoding to compress the given data
+	HuffmanOnly = zlib.HuffmanOnly
+	//RLE (run-length encoding) limits match distance to one
+	RLE = zlib.RLE
+	//Fixed disallows dynamic Huffman codes, thereby making it a simpler decoder
+	Fixed = zlib.Fixed
+	// DefaultStrategy is the default compression strategy that should be used for most appliances
+	DefaultStrategy = zlib.DefaultStrategy
+)
+
+const (
+	minCompression = NoCompression
+	maxCompression = BestCompression
+
+	minStrategy = DefaultStrategy
+	maxStrategy = Fixed
+
+	windowBits = 15
+)
@@ -0,0 +1,15 @@
+package gzip
+
+import (
+	"errors"
+)
+
+var (
+	errIsClosed        = errors.New("gzip: stream is already closed: you may not use this anymore")
+	errNoInput         = errors.New("gzip: no input provided: please provide at least 1 element")
+	errInvalidLevel    = errors.New("gzip: invalid compression level provided")
+	errInvalidStrategy = errors.New("gzip: invalid compression strategy provided")
+	errHeaderString    = errors.New("gzip: non-Latin-1 header string")
+	errHeaderExtra     = errors.New("gzip: extra field of the header is too large")
+	errNotAtMemberEnd  = errors.New("gzip: the current member has not been read completely")
+)
@@ -0,0 +1,89 @@
+package gzip
+
+import (
+	"time"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+const (
+	unknownOS = 255
+	maxExtra  = 0xffff
+)
+
+// Header is the gzip file header (RFC 1952).
+// Strings must be UTF-8 encoded and may only contain Unicode code points
+// U+0001 through U+00FF, due to limitations of the gzip format.
+// It mirrors compress/gzip.Header.
+type Header struct {
+	Comment string    // comment
+	Extra   []byte    // "extra data"
+	ModTime time.Time // modification time
+	Name    string    // file name
+	OS      byte      // operating system type
+}
+
+func (h *Header) toNative() (*native.GzipHeader, error) {
+	if len(h.Extra) > maxExtra {
+		return nil, errHeaderExtra
+	}
+	name, err := toLatin1(h.Name)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := toLatin1(h.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	var modTime uint32
+	if h.ModTime.After(time.Unix(0, 0)) {
+		modTime = uint32(h.ModTime.Unix())
+	}
+
+	return &native.GzipHeader{
+		Name:    name,
+		Comment: comment,
+		Extra:   h.Extra,
+		ModTime: modTime,
+		OS:      h.OS,
+	}, nil
+}
+
+// toLatin1 encodes s as ISO 8859-1 as required by the gzip format. An empty string results in nil.
+func toLatin1(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r == 0 || r > 0xff {
+			return nil, errHeaderString
+		}
+		b = append(b, byte(r))
+	}
+	return b, nil
+}
+
+func fromNative(h *native.GzipHeader) Header {
+	header := Header{
+		Comment: fromLatin1(h.Comment),
+		Extra:   h.Extra,
+		Name:    fromLatin1(h.Name),
+		OS:      h.OS,
+	}
+	if h.ModTime > 0 {
+		header.ModTime = time.Unix(int64(h.ModTime), 0)
+	}
+	return header
+}
+
+// fromLatin1 decodes ISO 8859-1 encoded b to a UTF-8 string
+func fromLatin1(b []byte) string {
+	r := make([]rune, len(b))
+	for i, v := range b {
+		r[i] = rune(v)
+	}
+	return string(r)
+}
@@ -0,0 +1,233 @@
+package gzip
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+const readSize = 32 * 1024
+
+// Reader decompresses gzip data from an underlying io.Reader or via the ReadBuffer method.
+// The Header holds the header of the member currently being read.
+// By default, concatenated members are read as one stream, like with compress/gzip.
+type Reader struct {
+	Header
+	r            io.Reader
+	decompressor *native.Decompressor
+	inBuffer     *bytes.Buffer
+	outBuffer    *bytes.Buffer
+	scratch      []byte
+	multistream  bool
+	eof          bool
+	err          error
+}
+
+// NewReader returns a new Reader, reading from r, and reads the header of the first member into Header.
+// It returns io.EOF if r is empty.
+// r may be nil if you only plan on using ReadBuffer.
+func NewReader(r io.Reader) (*Reader, error) {
+	c, err := native.NewDecompressorWindow(windowBits+native.GzipWindowOffset, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.EnableHeader(); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	z := &Reader{decompressor: c, multistream: true}
+	if err := z.reset(r); err != nil {
+		z.Close()
+		return nil, err
+	}
+	return z, nil
+}
+
+// Close closes the Reader by closing and freeing the underlying zlib stream.
+// It does not close the underlying io.Reader.
+// You should not forget to call this after being done with the reader.
+func (z *Reader) Close() error {
+	if err := checkClosed(z.decompressor); err != nil {
+		return err
+	}
+	z.inBuffer = nil
+	z.outBuffer = nil
+	return z.decompressor.Close()
+}
+
+// NativeMemory returns the number of bytes zlib has currently allocated for the Reader, or 0 once it has been closed.
+func (z *Reader) NativeMemory() int {
+	return z.decompressor.Memory()
+}
+
+// Multistream controls whether the reader reads through concatenated members (the default).
+// If disabled, Read returns io.EOF at the end of each member and NextMember advances to the next one,
+// so the header of each member may be inspected.
+func (z *Reader) Multistream(ok bool) {
+	z.multistream = ok
+}
+
+// Reset discards the Reader's state and makes it equivalent to the result of NewReader, but reading from r instead.
+// It reads the header of the first member into Header and returns io.EOF if r is empty.
+func (z *Reader) Reset(r io.Reader) error {
+	if err := checkClosed(z.decompressor); err != nil {
+		return err
+	}
+	if err := z.decompressor.Reset(); err != nil {
+		return err
+	}
+	return z.reset(r)
+}
+
+func (z *Reader) reset(r io.Reader) error {
+	z.r = r
+	z.inBuffer = &bytes.Buffer{}
+	z.outBuffer = &bytes.Buffer{}
+	z.eof = false
+	z.err = nil
+	z.Header = Header{}
+
+	if r == nil {
+		return nil
+	}
+	return z.readHeader()
+}
+
+// NextMember advances a Reader in non-multistream mode to the next member, once Read returned io.EOF,
+// and reads its header into Header. It returns io.EOF if there are no more members.
+func (z *Reader) NextMember() error {
+	if err := checkClosed(z.decompressor); err != nil {
+		return err
+	}
+	if z.err != io.EOF || z.outBuffer.Len() != 0 {
+		return errNotAtMemberEnd
+	}
+	return z.nextMember()
+}
+
+func (z *Reader) nextMember() error {
+	if err := z.decompressor.Reset(); err != nil {
+		return err
+	}
+	z.err = z.readHeader()
+	return z.err
+}
+
+// fill reads from the underlying reader into the input buffer until at least one byte has been read or EOF is reached
+func (z *Reader) fill() error {
+	if z.scratch == nil {
+		z.scratch = make([]byte, readSize)
+	}
+
+	for !z.eof {
+		n, err := z.r.Read(z.scratch)
+		z.inBuffer.Write(z.scratch[:n])
+		if err == io.EOF {
+			z.eof = true
+			return nil
+		}
+		if err != nil || n > 0 {
+			return err
+		}
+	}
+	return nil
+}
+
+// readHeader reads the header of the next member into Header
+func (z *Reader) readHeader() error {
+	first := true
+	for {
+		if z.inBuffer.Len() == 0 {
+			if err := z.fill(); err != nil {
+				return err
+			}
+			if z.inBuffer.Len() == 0 {
+				if first {
+					return io.EOF
+				}
+				return io.ErrUnexpectedEOF
+			}
+		}
+		first = false
+
+		n, done, err := z.decompressor.DecompressHeader(z.inBuffer.Bytes())
+		z.inBuffer.Next(n)
+		if err != nil {
+			return err
+		}
+		if done {
+			z.Header = fromNative(z.decompressor.Header())
+			return nil
+		}
+	}
+}
+
+// Read reads decompressed data from the underlying Reader into the provided buffer p.
+// To reuse the reader after an EOF condition, you have to Reset it.
+func (z *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, io.ErrShortBuffer
+	}
+	if err := checkClosed(z.decompressor); err != nil {
+		return 0, err
+	}
+
+	for z.outBuffer.Len() == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.decompress(p)
+	}
+	return z.outBuffer.Read(p)
+}
+
+// decompress decompresses the next chunk of input into the output buffer, using p as scratch space
+func (z *Reader) decompress(p []byte) error {
+	if z.inBuffer.Len() == 0 {
+		if err := z.fill(); err != nil {
+			return err
+		}
+		if z.inBuffer.Len() == 0 {
+			return io.ErrUnexpectedEOF
+		}
+	}
+
+	end, processed, out, err := z.decompressor.DecompressStream(z.inBuffer.Bytes(), p)
+	if err != nil {
+		return err
+	}
+	z.inBuffer.Next(processed)
+	z.outBuffer.Write(out)
+
+	if !end {
+		return nil
+	}
+	if !z.multistream {
+		return io.EOF
+	}
+	return z.nextMember()
+}
+
+// ReadBuffer takes a compressed gzip member, decompresses it to out in one go and returns out sliced accordingly.
+// The header of the member is read into Header.
+// If you don't know the output size beforehand, you may provide out == nil.
+// The method also returns the number n of bytes that were processed from the compressed slice.
+// If n < len(compressed) and err == nil then only the first n compressed bytes were in
+// a suitable gzip format and as such decompressed, which is the case for multiple members.
+// ReadBuffer resets the reader for new decompression.
+func (z *Reader) ReadBuffer(compressed, out []byte) (n int, decompressed []byte, err error) {
+	if len(compressed) == 0 {
+		return 0, nil, errNoInput
+	}
+	if err := checkClosed(z.decompressor); err != nil {
+		return 0, nil, err
+	}
+
+	n, decompressed, err = z.decompressor.Decompress(compressed, out)
+	if h := z.decompressor.Header(); h != nil {
+		z.Header = fromNative(h)
+	}
+	return n, decompressed, err
+}
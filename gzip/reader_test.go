@@ -0,0 +1,164 @@
+package gzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+// UNIT TESTS
+
+func TestRead(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := gzip.NewWriter(b)
+	w.Header = gzip.Header(testHeader)
+	w.Write(shortString)
+	w.Close()
+
+	r, err := NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	headerEquals(t, testHeader, gzip.Header(r.Header))
+
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, shortString, act.Bytes())
+}
+
+func TestRead_SmallBuffer(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := gzip.NewWriter(b)
+	for i := 0; i < 100; i++ {
+		w.Write(shortString)
+	}
+	w.Close()
+
+	r, err := NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act := &bytes.Buffer{}
+	p := make([]byte, 7)
+	for {
+		n, err := r.Read(p)
+		act.Write(p[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sliceEquals(t, bytes.Repeat(shortString, 100), act.Bytes())
+}
+
+func TestRead_Multistream(t *testing.T) {
+	b := testMembers(t)
+
+	r, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, bytes.Repeat(shortString, 3), act.Bytes())
+}
+
+func TestRead_NoMultistream(t *testing.T) {
+	b := testMembers(t)
+
+	r, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Multistream(false)
+
+	for i, name := range []string{"first", "second", "third"} {
+		if i > 0 {
+			if err := r.NextMember(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if r.Name != name {
+			t.Errorf("wrong member name: want %q; got %q", name, r.Name)
+		}
+
+		act := &bytes.Buffer{}
+		if _, err := io.Copy(act, r); err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, shortString, act.Bytes())
+	}
+
+	if err := r.NextMember(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last member: got %v", err)
+	}
+}
+
+func TestReadBuffer(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := gzip.NewWriter(b)
+	w.Header = gzip.Header(testHeader)
+	w.Write(shortString)
+	w.Close()
+
+	r, err := NewReader(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i := 0; i < 2; i++ {
+		n, out, err := r.ReadBuffer(b.Bytes(), nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if n != b.Len() {
+			t.Errorf("processed count doesn't match: want %d; got %d", b.Len(), n)
+		}
+		headerEquals(t, testHeader, gzip.Header(r.Header))
+		sliceEquals(t, shortString, out)
+	}
+}
+
+func TestNewReader_Empty(t *testing.T) {
+	if _, err := NewReader(&bytes.Buffer{}); err != io.EOF {
+		t.Errorf("expected io.EOF: got %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader([]byte{0x1f, 0x8b, 8})); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF: got %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader(shortString)); err == nil {
+		t.Error("expected an error for data not in the gzip format")
+	}
+}
+
+// HELPER
+
+func testMembers(t *testing.T) []byte {
+	w := NewWriter(nil)
+	defer w.Close()
+
+	var b []byte
+	for _, name := range []string{"first", "second", "third"} {
+		w.Name = name
+		member, err := w.WriteBuffer(shortString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, member...)
+	}
+	return b
+}
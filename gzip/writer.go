@@ -0,0 +1,221 @@
+package gzip
+
+import (
+	"io"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+// Writer compresses and writes given data in the gzip format to an underlying io.Writer.
+// The Header is written at the start of each gzip member. Its fields must be set
+// before the first call of Write, WriteBuffer, Flush or Close of a member.
+type Writer struct {
+	Header
+	w           io.Writer
+	level       int
+	strategy    int
+	compressor  *native.Compressor
+	wroteHeader bool
+	err         error
+}
+
+// NewWriter returns a new Writer with the underlying io.Writer to compress to.
+// w may be nil if you only plan on using WriteBuffer.
+// If the underlying c stream cannot be allocated, for instance due to zlib.SetMemoryBudget, every method
+// of the returned Writer fails with that error (and Reset panics). Use NewWriterLevel to get the error right away.
+func NewWriter(w io.Writer) *Writer {
+	zw, err := NewWriterLevel(w, DefaultCompression)
+	if err != nil {
+		return &Writer{Header: Header{OS: unknownOS}, w: w, level: DefaultCompression, err: err}
+	}
+	return zw
+}
+
+// NewWriterLevel performs like NewWriter but you may also specify the compression level.
+// w may be nil if you only plan on using WriteBuffer.
+func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
+	return NewWriterLevelStrategy(w, level, DefaultStrategy)
+}
+
+// NewWriterLevelStrategy performs like NewWriter but you may also specify the compression level and strategy.
+// w may be nil if you only plan on using WriteBuffer.
+func NewWriterLevelStrategy(w io.Writer, level, strategy int) (*Writer, error) {
+	if level != DefaultCompression && (level < minCompression || level > maxCompression) {
+		return nil, errInvalidLevel
+	}
+	if strategy < minStrategy || strategy > maxStrategy {
+		return nil, errInvalidStrategy
+	}
+	c, err := native.NewCompressorWindow(level, strategy, windowBits+native.GzipWindowOffset, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{Header: Header{OS: unknownOS}, w: w, level: level, strategy: strategy, compressor: c}, nil
+}
+
+// writeHeader hands the Header to the compressor if this has not happened yet for the current member
+func (z *Writer) writeHeader() error {
+	if z.wroteHeader {
+		return nil
+	}
+	if err := z.setHeader(); err != nil {
+		return err
+	}
+
+	z.wroteHeader = true
+	return nil
+}
+
+func (z *Writer) setHeader() error {
+	h, err := z.Header.toNative()
+	if err != nil {
+		return err
+	}
+	return z.compressor.SetHeader(h)
+}
+
+// WriteBuffer takes uncompressed data in, compresses it to a complete gzip member in out and returns out sliced accordingly.
+// The member carries the current Header.
+// If you pass nil for out, this function will allocate a sufficiently large buffer.
+// Use this for whole-buffered, in-memory data.
+func (z *Writer) WriteBuffer(in, out []byte) ([]byte, error) {
+	if len(in) == 0 {
+		return nil, errNoInput
+	}
+	if err := z.check(); err != nil {
+		return nil, err
+	}
+
+	if err := z.setHeader(); err != nil {
+		return nil, err
+	}
+
+	if out == nil {
+		out = make([]byte, z.compressor.Bound(len(in)))
+	}
+	return z.compressor.Compress(in, out)
+}
+
+// Write compresses the given data p and writes it to the underlying io.Writer.
+// The data is not necessarily written to the underlying writer, if no Flush is called.
+// It returns the number of *uncompressed* bytes written to the underlying io.Writer in case of err = nil,
+// or the number of *compressed* bytes in case of err != nil.
+// Please consider using WriteBuffer as it might be more convenient for your use case.
+func (z *Writer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return -1, errNoInput
+	}
+	if err := z.check(); err != nil {
+		return -1, err
+	}
+	if err := z.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	out, err := z.compressor.CompressStream(p)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := z.write(out)
+	if err != nil {
+		return n, err
+	}
+	return len(p), nil
+}
+
+func (z *Writer) write(b []byte) (int, error) {
+	if z.w == nil {
+		return 0, nil
+	}
+
+	n := 0
+	for n < len(b) {
+		inc, err := z.w.Write(b[n:])
+		if err != nil {
+			return n, err
+		}
+		n += inc
+	}
+	return n, nil
+}
+
+// Flush writes compressed buffered data to the underlying writer.
+func (z *Writer) Flush() error {
+	if err := z.check(); err != nil {
+		return err
+	}
+	if err := z.writeHeader(); err != nil {
+		return err
+	}
+
+	b, err := z.compressor.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = z.write(b)
+	return err
+}
+
+// Close closes the writer by writing the gzip trailer and any unwritten data to the underlying writer.
+// You should not forget to call this after being done with the writer.
+func (z *Writer) Close() error {
+	if err := z.check(); err != nil {
+		return err
+	}
+
+	err := z.writeHeader()
+	b, cerr := z.compressor.Close()
+	if err != nil {
+		return err
+	}
+	if cerr != nil {
+		return cerr
+	}
+
+	_, err = z.write(b)
+	return err
+}
+
+// NativeMemory returns the number of bytes zlib has currently allocated for the Writer, or 0 once it has been closed.
+func (z *Writer) NativeMemory() int {
+	if z.err != nil {
+		return 0
+	}
+	return z.compressor.Memory()
+}
+
+// Reset finishes the current gzip member by writing its trailer to the current underlying writer
+// and starts a new member on the new underlying writer w. The Header is reset to its initial state
+// and may be set again for the new member.
+// Resetting to the same underlying writer results in multiple members, which gzip readers concatenate.
+// This will panic if the writer could not be created, has already been closed, could not be reset
+// or could not write to the current underlying writer.
+func (z *Writer) Reset(w io.Writer) {
+	if err := z.check(); err != nil {
+		panic(err)
+	}
+	if err := z.writeHeader(); err != nil {
+		panic(err)
+	}
+
+	b, err := z.compressor.Reset()
+	if err != nil {
+		panic(err)
+	}
+	if _, err := z.write(b); err != nil {
+		panic(err)
+	}
+
+	z.w = w
+	z.Header = Header{OS: unknownOS}
+	z.wroteHeader = false
+}
+
+// check returns the error the Writer could not be created with, or an error if it has been closed
+func (z *Writer) check() error {
+	if z.err != nil {
+		return z.err
+	}
+	return checkClosed(z.compressor)
+}
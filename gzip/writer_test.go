@@ -0,0 +1,243 @@
+package gzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+var shortString = []byte("hello, world\nhello, world\nhello, world\nhello, world\nhello, world\nhello, world\nhello, world\nhello, world\nhello, world\nhello, world\nhello, world\n")
+
+var testHeader = Header{
+	Comment: "comment with ümlaut",
+	Extra:   []byte{'a', 'b', 2, 0, 'x', 'y'},
+	ModTime: time.Unix(1600000000, 0),
+	Name:    "file.txt",
+	OS:      3,
+}
+
+// UNIT TESTS
+
+func TestWrite(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Header = testHeader
+
+	_, err := w.Write(shortString)
+	if err != nil {
+		t.Error(err)
+	}
+	w.Flush()
+	_, err = w.Write(shortString)
+	if err != nil {
+		t.Error(err)
+	}
+	w.Close()
+
+	r, err := gzip.NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerEquals(t, testHeader, r.Header)
+
+	act := &bytes.Buffer{}
+	_, err = io.Copy(act, r)
+	if err != nil {
+		t.Error(err)
+	}
+
+	sliceEquals(t, append(shortString, shortString...), act.Bytes())
+}
+
+func TestWrite_DefaultHeader(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Write(shortString)
+	w.Close()
+
+	std := &bytes.Buffer{}
+	sw := gzip.NewWriter(std)
+	sw.Write(shortString)
+	sw.Close()
+
+	// everything but the XFL byte, which depends on the level, must match the standard library's header
+	h, stdH := b.Bytes()[:10], std.Bytes()[:10]
+	h[8], stdH[8] = 0, 0
+	sliceEquals(t, stdH, h)
+}
+
+func TestWriteBuffer(t *testing.T) {
+	w, err := NewWriterLevel(nil, BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Header = testHeader
+
+	for i := 0; i < 2; i++ {
+		b, err := w.WriteBuffer(shortString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		headerEquals(t, testHeader, r.Header)
+
+		act := &bytes.Buffer{}
+		_, err = io.Copy(act, r)
+		if err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, shortString, act.Bytes())
+	}
+}
+
+func TestWriteBuffer_Incompressible(t *testing.T) {
+	w := NewWriter(nil)
+	defer w.Close()
+
+	in := make([]byte, 100000)
+	for i := range in {
+		in[i] = byte(i * 7919 >> 3)
+	}
+
+	b, err := w.WriteBuffer(in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := &bytes.Buffer{}
+	_, err = io.Copy(act, r)
+	if err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, in, act.Bytes())
+}
+
+func TestReset_MultipleMembers(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Name = "first"
+	w.Write(shortString)
+	w.Reset(b)
+	w.Name = "second"
+	w.Write(shortString)
+	w.Close()
+
+	r, err := gzip.NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Multistream(false)
+
+	for _, name := range []string{"first", "second"} {
+		if r.Name != name {
+			t.Errorf("wrong member name: want %q; got %q", name, r.Name)
+		}
+
+		act := &bytes.Buffer{}
+		_, err = io.Copy(act, r)
+		if err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, shortString, act.Bytes())
+
+		if err := r.Reset(b); err != nil && err != io.EOF {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWrite_InvalidHeader(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+	w.Name = "Ā"
+	if _, err := w.Write(shortString); err != errHeaderString {
+		t.Errorf("expected %v; got %v", errHeaderString, err)
+	}
+	w.Close()
+}
+
+func TestWrite_GzipCommand(t *testing.T) {
+	path, err := exec.LookPath("gzip")
+	if err != nil {
+		t.Skip("gzip command not available")
+	}
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Header = testHeader
+	w.Write(shortString)
+	w.Reset(b)
+	w.Write(shortString)
+	w.Close()
+
+	cmd := exec.Command(path, "-d", "-c")
+	cmd.Stdin = b
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sliceEquals(t, append(shortString, shortString...), out)
+}
+
+// HELPER
+
+func headerEquals(t *testing.T, expected Header, actual gzip.Header) {
+	if expected.Name != actual.Name || expected.Comment != actual.Comment || expected.OS != actual.OS ||
+		!expected.ModTime.Equal(actual.ModTime) || !bytes.Equal(expected.Extra, actual.Extra) {
+		t.Errorf("headers differ: want %+v; got %+v", expected, actual)
+	}
+}
+
+func sliceEquals(t *testing.T, expected, actual []byte) {
+	if len(expected) != len(actual) {
+		t.Errorf("inequal size: want %d; got %d", len(expected), len(actual))
+		return
+	}
+	for i, v := range expected {
+		if v != actual[i] {
+			t.Errorf("slices differ at index %d: want %d; got %d", i, v, actual[i])
+			t.FailNow()
+		}
+	}
+}
+
+func TestWriter_NativeMemory(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+	if w.NativeMemory() == 0 {
+		t.Error("want the memory of the stream; got 0")
+	}
+	w.Close()
+	if act := w.NativeMemory(); act != 0 {
+		t.Errorf("want no memory after Close; got %d", act)
+	}
+}
+
+func TestNewWriter_MemoryBudget(t *testing.T) {
+	native.SetMemoryBudget(1)
+	defer native.SetMemoryBudget(0)
+
+	w := NewWriter(&bytes.Buffer{})
+	if _, err := w.Write(shortString); !errors.Is(err, native.ErrMemory) {
+		t.Errorf("Write: want %v; got %v", native.ErrMemory, err)
+	}
+	if err := w.Close(); !errors.Is(err, native.ErrMemory) {
+		t.Errorf("Close: want %v; got %v", native.ErrMemory, err)
+	}
+	if w.NativeMemory() != 0 {
+		t.Errorf("want no memory; got %d", w.NativeMemory())
+	}
+}
@@ -0,0 +1,95 @@
+package httpzlib
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the content coding among gzip and deflate the client prefers according to the given
+// Accept-Encoding header values, or "" if it accepts neither. gzip wins ties, as deflate has been
+// implemented inconsistently by clients in the past.
+func negotiate(accept []string) string {
+	q := map[string]float64{}
+	wildcard := -1.0
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			coding, weight, ok := parseCoding(part)
+			if !ok {
+				continue
+			}
+			switch coding {
+			case "*":
+				wildcard = weight
+			case "x-gzip":
+				q[encodingGzip] = weight
+			default:
+				q[coding] = weight
+			}
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{encodingGzip, encodingDeflate} {
+		weight, ok := q[coding]
+		if !ok {
+			weight = wildcard
+		}
+		if weight > bestQ {
+			best, bestQ = coding, weight
+		}
+	}
+	return best
+}
+
+// parseCoding parses a content coding with an optional weight like "gzip;q=0.8"
+func parseCoding(s string) (string, float64, bool) {
+	params := strings.Split(s, ";")
+	coding := strings.ToLower(strings.TrimSpace(params[0]))
+	if coding == "" {
+		return "", 0, false
+	}
+
+	weight := 1.0
+	for _, param := range params[1:] {
+		param = strings.TrimSpace(param)
+		if !strings.HasPrefix(param, "q=") && !strings.HasPrefix(param, "Q=") {
+			continue
+		}
+		w, err := strconv.ParseFloat(param[2:], 64)
+		if err != nil || w < 0 || w > 1 {
+			return "", 0, false
+		}
+		weight = w
+	}
+	return coding, weight, true
+}
+
+// contentEncoding returns the content coding of a body if it is gzip or deflate and the only one
+func contentEncoding(values []string) string {
+	if len(values) != 1 {
+		return ""
+	}
+	switch coding := strings.ToLower(strings.TrimSpace(values[0])); coding {
+	case encodingGzip, "x-gzip":
+		return encodingGzip
+	case encodingDeflate:
+		return encodingDeflate
+	}
+	return ""
+}
+
+// matchContentType reports whether the media type of contentType is in types,
+// which holds lower case media types or wildcards like "text/*"
+func matchContentType(contentType string, types []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
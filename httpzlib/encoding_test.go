@@ -0,0 +1,41 @@
+package httpzlib
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		accept []string
+		exp    string
+	}{
+		{nil, ""},
+		{[]string{""}, ""},
+		{[]string{"deflate"}, "deflate"},
+		{[]string{"deflate", "gzip"}, "gzip"},
+		{[]string{"Deflate;Q=1, GZIP;q=0.999"}, "deflate"},
+		{[]string{"gzip;q=2, deflate"}, "deflate"},
+		{[]string{"gzip;q=abc"}, ""},
+		{[]string{"*;q=0.5, gzip;q=0.4"}, "deflate"},
+		{[]string{"*;q=0"}, ""},
+	} {
+		if act := negotiate(tc.accept); act != tc.exp {
+			t.Errorf("%q: want %q; got %q", tc.accept, tc.exp, act)
+		}
+	}
+}
+
+func TestMatchContentType(t *testing.T) {
+	types := []string{"text/*", "application/json"}
+	for contentType, exp := range map[string]bool{
+		"text/html":                       true,
+		"text/plain; charset=utf-8":       true,
+		"Application/JSON; charset=utf-8": true,
+		"application/javascript":          false,
+		"textual/html":                    false,
+		"":                                false,
+		"text":                            false,
+	} {
+		if act := matchContentType(contentType, types); act != exp {
+			t.Errorf("%q: want %v; got %v", contentType, exp, act)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package httpzlib
+
+import (
+	"errors"
+)
+
+var (
+	errInvalidLevel    = errors.New("httpzlib: invalid compression level provided")
+	errInvalidPoolSize = errors.New("httpzlib: invalid pool size provided")
+
+	errHijackCompressed = errors.New("httpzlib: cannot hijack the connection of a response being compressed")
+	errNotHijacker      = errors.New("httpzlib: the underlying http.ResponseWriter does not implement http.Hijacker")
+
+	// ErrBodyTooLarge is returned when reading a decompressed body that exceeds the configured size limit
+	ErrBodyTooLarge = errors.New("httpzlib: decompressed body exceeds the size limit")
+)
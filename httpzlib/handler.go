@@ -0,0 +1,300 @@
+// Package httpzlib provides net/http middleware and a client transport handling the deflate and gzip
+// content codings with native zlib, reusing its streams across requests.
+package httpzlib
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/4kills/go-zlib"
+)
+
+const (
+	// DefaultMinSize is the default size in bytes below which responses are not compressed
+	DefaultMinSize = 1024
+	// DefaultMaxBodySize is the default limit of decompressed request and response bodies
+	DefaultMaxBodySize = 32 << 20
+)
+
+// DefaultContentTypes are the media types compressed by default
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/xhtml+xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// Options configures the middleware returned by Handler.
+type Options struct {
+	// Level is the compression level of responses, following the rules of zlib.Options.
+	Level int
+	// MinSize is the size in bytes below which responses are sent uncompressed, as it does not pay off.
+	// Responses flushed before reaching it are sent uncompressed as well. The zero value means DefaultMinSize.
+	MinSize int
+	// ContentTypes are the media types of responses to compress. Entries like "text/*" match all subtypes.
+	// Responses without Content-Type are sniffed like by net/http. The zero value means DefaultContentTypes.
+	ContentTypes []string
+	// MaxRequestBodySize limits the size of decompressed request bodies; reading past it fails with ErrBodyTooLarge.
+	// The zero value means DefaultMaxBodySize, a negative value means no limit.
+	MaxRequestBodySize int64
+	// PoolSize is the number of idle native streams kept for each content coding.
+	// The zero value means runtime.GOMAXPROCS(0).
+	PoolSize int
+}
+
+func (o Options) validate() (Options, error) {
+	level, err := zlib.OptionsLevel(o.Level)
+	if err != nil {
+		return o, errInvalidLevel
+	}
+	o.Level = level
+	if o.MinSize == 0 {
+		o.MinSize = DefaultMinSize
+	}
+	if o.ContentTypes == nil {
+		o.ContentTypes = DefaultContentTypes
+	}
+	types := make([]string, len(o.ContentTypes))
+	for i, t := range o.ContentTypes {
+		types[i] = strings.ToLower(t)
+	}
+	o.ContentTypes = types
+	if o.MaxRequestBodySize == 0 {
+		o.MaxRequestBodySize = DefaultMaxBodySize
+	}
+
+	if o.PoolSize == 0 {
+		o.PoolSize = runtime.GOMAXPROCS(0)
+	}
+	if o.PoolSize < 0 {
+		return o, errInvalidPoolSize
+	}
+	return o, nil
+}
+
+type handler struct {
+	next    http.Handler
+	opts    Options
+	writers map[string]*writerPool
+	readers *readerPool
+}
+
+// Handler returns middleware compressing the responses of next with gzip or deflate, whichever the client prefers
+// according to Accept-Encoding, and decompressing request bodies sent with Content-Encoding gzip or deflate.
+// Responses are compressed if their media type is allowed, they are at least MinSize bytes and the handler
+// has not set a Content-Encoding itself. Vary: Accept-Encoding is added to every response of an allowed media type.
+// Protocol upgrades, like WebSocket, pass through uncompressed, both with 101 Switching Protocols and http.Hijacker.
+// The native streams are pooled, so the returned http.Handler does not need to be closed.
+func Handler(next http.Handler, opts Options) (http.Handler, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &handler{
+		next: next,
+		opts: opts,
+		writers: map[string]*writerPool{
+			encodingGzip:    newWriterPool(encodingGzip, opts.Level, opts.PoolSize),
+			encodingDeflate: newWriterPool(encodingDeflate, opts.Level, opts.PoolSize),
+		},
+		readers: newReaderPool(opts.PoolSize),
+	}, nil
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if encoding := contentEncoding(r.Header["Content-Encoding"]); encoding != "" && r.Body != nil && r.Body != http.NoBody {
+		decoder, err := h.readers.get(encoding, r.Body)
+		if err != nil {
+			http.Error(w, "malformed "+encoding+" request body", http.StatusBadRequest)
+			return
+		}
+		b := &body{r: decoder, body: r.Body, pool: h.readers, limit: h.opts.MaxRequestBodySize}
+		defer b.Close()
+
+		r.Body = b
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+	}
+
+	rw := &responseWriter{
+		ResponseWriter: w,
+		h:              h,
+		encoding:       negotiate(r.Header["Accept-Encoding"]),
+		head:           r.Method == http.MethodHead,
+	}
+	defer rw.close()
+	h.next.ServeHTTP(rw, r)
+}
+
+// responseWriter buffers the start of a response until it is known whether to compress it
+type responseWriter struct {
+	http.ResponseWriter
+	h           *handler
+	encoding    string
+	head        bool
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         *encoder
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	if status == http.StatusSwitchingProtocols {
+		// the connection is taken over by another protocol, which is not compressed
+		rw.wroteHeader = true
+		rw.decided = true
+		rw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if status >= 100 && status < 200 {
+		rw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = status
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.decided {
+		if rw.enc != nil {
+			return rw.enc.Write(p)
+		}
+		return rw.ResponseWriter.Write(p)
+	}
+
+	rw.buf = append(rw.buf, p...)
+	if len(rw.buf) < rw.h.opts.MinSize {
+		return len(p), nil
+	}
+	if err := rw.decide(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends the buffered response to the client, which is only compressed if it has reached MinSize.
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if !rw.decided {
+		if rw.decide() != nil {
+			return
+		}
+	}
+	if rw.enc != nil && rw.enc.Flush() != nil {
+		return
+	}
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, for example to upgrade it to WebSocket, unless the response
+// is already being compressed. Data written before is sent uncompressed.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if rw.enc != nil {
+		return nil, nil, errHijackCompressed
+	}
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errNotHijacker
+	}
+
+	if !rw.decided {
+		rw.wroteHeader = true
+		rw.decided = true
+		buf := rw.buf
+		rw.buf = nil
+		if len(buf) != 0 {
+			if _, err := rw.ResponseWriter.Write(buf); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// decide writes the header and the buffered data, compressed if the response qualifies for it
+func (rw *responseWriter) decide() error {
+	rw.decided = true
+	header := rw.Header()
+	if _, ok := header["Content-Type"]; !ok && len(rw.buf) != 0 {
+		header.Set("Content-Type", http.DetectContentType(rw.buf))
+	}
+
+	if rw.compressible() {
+		header.Add("Vary", "Accept-Encoding")
+		if rw.encoding != "" && len(rw.buf) >= rw.h.opts.MinSize {
+			enc, err := rw.h.writers[rw.encoding].get(rw.ResponseWriter)
+			if err != nil {
+				rw.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+				return err
+			}
+			rw.enc = enc
+			header.Set("Content-Encoding", rw.encoding)
+			header.Del("Content-Length")
+			header.Del("Accept-Ranges")
+		}
+	}
+	rw.ResponseWriter.WriteHeader(rw.status)
+
+	buf := rw.buf
+	rw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if rw.enc != nil {
+		_, err = rw.enc.Write(buf)
+	} else {
+		_, err = rw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compressible reports whether the response may be compressed regardless of its size and the client
+func (rw *responseWriter) compressible() bool {
+	switch {
+	case rw.head, rw.status < 200, rw.status == http.StatusNoContent,
+		rw.status == http.StatusPartialContent, rw.status == http.StatusNotModified:
+		return false
+	case rw.Header().Get("Content-Encoding") != "":
+		return false
+	}
+	return matchContentType(rw.Header().Get("Content-Type"), rw.h.opts.ContentTypes)
+}
+
+// close sends what is left of the response and returns the encoder to its pool
+func (rw *responseWriter) close() {
+	if !rw.decided {
+		if !rw.wroteHeader {
+			rw.WriteHeader(http.StatusOK)
+		}
+		rw.decide()
+	}
+	if rw.enc != nil {
+		rw.enc.finish()
+		rw.h.writers[rw.encoding].put(rw.enc)
+		rw.enc = nil
+	}
+}
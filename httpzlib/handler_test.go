@@ -0,0 +1,412 @@
+package httpzlib
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	gozlib "github.com/4kills/go-zlib"
+)
+
+var testBody = bytes.Repeat([]byte("<p>httpzlib compresses this paragraph.</p>\n"), 100)
+
+func decodeBody(t *testing.T, encoding string, b []byte) []byte {
+	t.Helper()
+
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(b))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(b))
+	default:
+		return b
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return act
+}
+
+func newTestHandler(t *testing.T, opts Options, next http.HandlerFunc) http.Handler {
+	t.Helper()
+
+	h, err := Handler(next, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	h := newTestHandler(t, Options{Level: 6}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(len(testBody)))
+		// many small writes
+		for i := 0; i < len(testBody); i += 100 {
+			w.Write(testBody[i:min(i+100, len(testBody))])
+		}
+	})
+
+	for _, tc := range []struct {
+		accept, encoding string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"x-gzip", "gzip"},
+		{"*", "gzip"},
+		{"*;q=0, deflate;q=0.1", "deflate"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"br, identity", ""},
+	} {
+		// several times to reuse pooled streams
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept-Encoding", tc.accept)
+			}
+			rec := serve(h, req)
+
+			if act := rec.Header().Get("Content-Encoding"); act != tc.encoding {
+				t.Errorf("%q: want encoding %q; got %q", tc.accept, tc.encoding, act)
+			}
+			if act := rec.Header().Get("Vary"); act != "Accept-Encoding" {
+				t.Errorf("%q: wrong Vary header %q", tc.accept, act)
+			}
+			if tc.encoding != "" && rec.Header().Get("Content-Length") != "" {
+				t.Errorf("%q: Content-Length of a compressed response must be removed", tc.accept)
+			}
+			if act := decodeBody(t, tc.encoding, rec.Body.Bytes()); !bytes.Equal(act, testBody) {
+				t.Errorf("%q: decoded body does not match", tc.accept)
+			}
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func TestHandler_Uncompressed(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handler http.HandlerFunc
+		method  string
+		vary    bool
+	}{
+		"small": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(testBody[:DefaultMinSize-1])
+		}, http.MethodGet, true},
+		"flushed": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(testBody[:10])
+			w.(http.Flusher).Flush()
+			w.Write(testBody[10:])
+		}, http.MethodGet, true},
+		"image": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(testBody)
+		}, http.MethodGet, false},
+		"encoded": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			w.Write(testBody)
+		}, http.MethodGet, false},
+		"partial": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(testBody)
+		}, http.MethodGet, false},
+		"head": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(testBody)
+		}, http.MethodHead, false},
+		"empty": {func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet, false},
+	} {
+		h := newTestHandler(t, Options{Level: 6}, tc.handler)
+		req := httptest.NewRequest(tc.method, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		rec := serve(h, req)
+
+		if act := rec.Header().Get("Content-Encoding"); act != "" && act != "br" {
+			t.Errorf("%s: response should not be compressed: %q", name, act)
+		}
+		if vary := rec.Header().Get("Vary") != ""; vary != tc.vary {
+			t.Errorf("%s: want Vary %v; got %v", name, tc.vary, vary)
+		}
+	}
+}
+
+func TestHandler_Sniff(t *testing.T) {
+	h := newTestHandler(t, Options{Level: 6, ContentTypes: []string{"TEXT/HTML"}}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testBody)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate")
+	rec := serve(h, req)
+
+	if act := rec.Header().Get("Content-Type"); !strings.HasPrefix(act, "text/html") {
+		t.Errorf("wrong sniffed content type %q", act)
+	}
+	if act := decodeBody(t, rec.Header().Get("Content-Encoding"), rec.Body.Bytes()); !bytes.Equal(act, testBody) {
+		t.Error("decoded body does not match")
+	}
+}
+
+func TestHandler_Streaming(t *testing.T) {
+	chunks := make(chan []byte)
+	h := newTestHandler(t, Options{Level: 6, MinSize: 1}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for c := range chunks {
+			w.Write(c)
+			w.(http.Flusher).Flush()
+		}
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	// the header is only sent with the first flush
+	go func() { chunks <- []byte("data: first\n\n") }()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("response is not compressed: %v", resp.Header)
+	}
+
+	r, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every flushed event arrives before the response ends
+	for _, event := range []string{"data: first\n\n", "data: second\n\n"} {
+		if event != "data: first\n\n" {
+			chunks <- []byte(event)
+		}
+		act := make([]byte, len(event))
+		if _, err := io.ReadFull(r, act); err != nil {
+			t.Fatal(err)
+		}
+		if string(act) != event {
+			t.Errorf("want %q; got %q", event, act)
+		}
+	}
+	close(chunks)
+	if rest, err := ioutil.ReadAll(r); err != nil || len(rest) != 0 {
+		t.Errorf("want the end of the response; got %q, %v", rest, err)
+	}
+}
+
+func TestHandler_RequestBody(t *testing.T) {
+	h := newTestHandler(t, Options{Level: 6, MaxRequestBodySize: int64(len(testBody))}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" || r.ContentLength != -1 {
+			t.Error("the request still looks compressed")
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if errors.Is(err, ErrBodyTooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(b)
+	})
+
+	encode := func(encoding string, data []byte) []byte {
+		b := &bytes.Buffer{}
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(b)
+		case "deflate":
+			w = zlib.NewWriter(b)
+		case "raw":
+			w, _ = flate.NewWriter(b, flate.DefaultCompression)
+		}
+		w.Write(data)
+		w.Close()
+		return b.Bytes()
+	}
+
+	for _, tc := range []struct {
+		encoding, header string
+		data             []byte
+		status           int
+	}{
+		{"gzip", "gzip", testBody, http.StatusOK},
+		{"gzip", "GZIP", testBody, http.StatusOK},
+		{"deflate", "deflate", testBody, http.StatusOK},
+		{"raw", "deflate", testBody, http.StatusOK},
+		{"gzip", "gzip", append(testBody, 'x'), http.StatusRequestEntityTooLarge},
+		{"deflate", "deflate", append(testBody, 'x'), http.StatusRequestEntityTooLarge},
+	} {
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encode(tc.encoding, tc.data)))
+			req.Header.Set("Content-Encoding", tc.header)
+			rec := serve(h, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("%s, %q: want status %d; got %d", tc.encoding, tc.header, tc.status, rec.Code)
+			}
+			if tc.status == http.StatusOK && !bytes.Equal(rec.Body.Bytes(), tc.data) {
+				t.Errorf("%s, %q: the handler did not receive the decompressed body", tc.encoding, tc.header)
+			}
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	if rec := serve(h, req); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for a malformed body; got %d", http.StatusBadRequest, rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not deflate"))
+	req.Header.Set("Content-Encoding", "deflate")
+	if rec := serve(h, req); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for a malformed body; got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandler_Level(t *testing.T) {
+	for _, tc := range []struct {
+		level      int
+		compressed bool
+	}{
+		{0, true},
+		{gozlib.BestSpeed, true},
+		{gozlib.StoreOnly, false},
+	} {
+		h := newTestHandler(t, Options{Level: tc.level}, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(testBody)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "deflate")
+		rec := serve(h, req)
+
+		if compressed := rec.Body.Len() < len(testBody); compressed != tc.compressed {
+			t.Errorf("level %d: want compressed %t; got %d bytes for %d", tc.level, tc.compressed, rec.Body.Len(), len(testBody))
+		}
+		if act := decodeBody(t, rec.Header().Get("Content-Encoding"), rec.Body.Bytes()); !bytes.Equal(act, testBody) {
+			t.Errorf("level %d: decoded body does not match", tc.level)
+		}
+	}
+}
+
+func TestHandler_Invalid(t *testing.T) {
+	next := http.NotFoundHandler()
+	if _, err := Handler(next, Options{Level: 10}); err != errInvalidLevel {
+		t.Errorf("want %v; got %v", errInvalidLevel, err)
+	}
+	if _, err := Handler(next, Options{PoolSize: -1}); err != errInvalidPoolSize {
+		t.Errorf("want %v; got %v", errInvalidPoolSize, err)
+	}
+}
+
+func TestHandler_SwitchingProtocols(t *testing.T) {
+	h := newTestHandler(t, Options{Level: 6, MinSize: 1}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Upgrade", "example")
+		w.WriteHeader(http.StatusSwitchingProtocols)
+		w.Write(testBody)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := serve(h, req)
+	if rec.Code != http.StatusSwitchingProtocols {
+		t.Errorf("want status %d; got %d", http.StatusSwitchingProtocols, rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), testBody) {
+		t.Error("the upgraded connection has been compressed")
+	}
+}
+
+func TestHandler_Hijack(t *testing.T) {
+	h := newTestHandler(t, Options{Level: 6}, func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("the ResponseWriter does not implement http.Hijacker")
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: example\r\nConnection: Upgrade\r\n\r\n")
+		buf.WriteString("upgraded")
+		buf.Flush()
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example\r\nAccept-Encoding: gzip\r\nUpgrade: example\r\nConnection: Upgrade\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("want an uncompressed upgrade; got %d, %v", resp.StatusCode, resp.Header)
+	}
+	if act, err := ioutil.ReadAll(br); err != nil || string(act) != "upgraded" {
+		t.Errorf("want %q on the upgraded connection; got %q, %v", "upgraded", act, err)
+	}
+}
+
+func TestHandler_HijackCompressed(t *testing.T) {
+	h := newTestHandler(t, Options{Level: 6, MinSize: 1}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(testBody)
+		if _, _, err := w.(http.Hijacker).Hijack(); err != errHijackCompressed {
+			t.Errorf("want %v; got %v", errHijackCompressed, err)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if rec := serve(h, req); !bytes.Equal(decodeBody(t, "gzip", rec.Body.Bytes()), testBody) {
+		t.Error("decompressed body does not match")
+	}
+}
@@ -0,0 +1,243 @@
+package httpzlib
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/4kills/go-zlib"
+	"github.com/4kills/go-zlib/gzip"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// sink forwards compressed data to the current destination and keeps the first error instead of returning it,
+// as finishing a stream by Reset panics on write errors, which must not bring down a server
+type sink struct {
+	w   io.Writer
+	err error
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	if s.err == nil && s.w != nil {
+		_, s.err = s.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// encodingWriter is implemented by the zlib and gzip Writers
+type encodingWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoder compresses a response with a pooled Writer
+type encoder struct {
+	w    encodingWriter
+	sink *sink
+}
+
+func (e *encoder) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if _, err := e.w.Write(p); err != nil {
+		return 0, err
+	}
+	return len(p), e.sink.err
+}
+
+func (e *encoder) Flush() error {
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+	return e.sink.err
+}
+
+// finish writes the end of the stream and detaches the encoder from its destination
+func (e *encoder) finish() error {
+	e.w.Reset(e.sink)
+	err := e.sink.err
+	e.sink.w, e.sink.err = nil, nil
+	return err
+}
+
+// writerPool keeps up to a fixed number of idle Writers of one content coding, as their native streams
+// are not freed by the garbage collector
+type writerPool struct {
+	encoding string
+	level    int
+	idle     chan *encoder
+}
+
+func newWriterPool(encoding string, level, size int) *writerPool {
+	return &writerPool{encoding: encoding, level: level, idle: make(chan *encoder, size)}
+}
+
+// get returns an encoder writing to w
+func (p *writerPool) get(w io.Writer) (*encoder, error) {
+	select {
+	case e := <-p.idle:
+		e.sink.w = w
+		return e, nil
+	default:
+	}
+
+	e := &encoder{sink: &sink{w: w}}
+	var err error
+	if p.encoding == encodingGzip {
+		e.w, err = gzip.NewWriterLevel(e.sink, p.level)
+	} else {
+		e.w, err = zlib.NewWriterLevel(e.sink, p.level)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// put returns a finished encoder to the pool or closes it if the pool is full
+func (p *writerPool) put(e *encoder) {
+	select {
+	case p.idle <- e:
+	default:
+		e.w.Close()
+	}
+}
+
+// readerPool keeps up to a fixed number of idle Readers for each content coding
+type readerPool struct {
+	gzip chan *gzip.Reader
+	zlib chan *zlib.Reader
+}
+
+func newReaderPool(size int) *readerPool {
+	return &readerPool{gzip: make(chan *gzip.Reader, size), zlib: make(chan *zlib.Reader, size)}
+}
+
+// get returns a Reader decompressing r in the given content coding, which is either gzip or deflate.
+// deflate is decompressed as zlib or raw DEFLATE, as both are in use.
+func (p *readerPool) get(encoding string, r io.Reader) (io.Reader, error) {
+	if encoding == encodingGzip {
+		select {
+		case zr := <-p.gzip:
+			if err := zr.Reset(r); err != nil {
+				zr.Close()
+				return nil, err
+			}
+			return zr, nil
+		default:
+		}
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	}
+
+	select {
+	case zr := <-p.zlib:
+		if err := zr.Reset(r, nil); err != nil {
+			zr.Close()
+			return nil, err
+		}
+		return zr, nil
+	default:
+	}
+	zr, err := zlib.NewAutoReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zr, nil
+}
+
+// put returns a Reader obtained from get to the pool or closes it if the pool is full
+func (p *readerPool) put(r io.Reader) {
+	switch zr := r.(type) {
+	case *gzip.Reader:
+		select {
+		case p.gzip <- zr:
+		default:
+			zr.Close()
+		}
+	case *zlib.Reader:
+		// let go of the underlying reader
+		if err := zr.Reset(nil, nil); err != nil {
+			zr.Close()
+			return
+		}
+		select {
+		case p.zlib <- zr:
+		default:
+			zr.Close()
+		}
+	}
+}
+
+// body decompresses a request or response body with a pooled Reader and fails with ErrBodyTooLarge
+// once more than limit bytes are decompressed, unless limit is negative.
+// If the Reader is nil, it is obtained for encoding by the first Read.
+type body struct {
+	r        io.Reader
+	encoding string
+	body     io.ReadCloser
+	pool     *readerPool
+	limit    int64
+	err      error
+	closed   bool
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.r == nil {
+		if b.r, b.err = b.pool.get(b.encoding, b.body); b.err != nil {
+			return 0, b.err
+		}
+	}
+
+	// read a byte more than allowed to detect an exceeded limit
+	if b.limit >= 0 && int64(len(p)) > b.limit+1 {
+		p = p[:b.limit+1]
+	}
+	n, err := b.r.Read(p)
+	if b.limit >= 0 {
+		if int64(n) > b.limit {
+			n, err = int(b.limit), ErrBodyTooLarge
+		}
+		b.limit -= int64(n)
+	}
+
+	if err != nil {
+		b.err = err
+		b.release()
+	}
+	return n, err
+}
+
+// Close returns the Reader to the pool and closes the compressed body.
+func (b *body) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	if b.err == nil {
+		b.err = http.ErrBodyReadAfterClose
+	}
+	b.release()
+	return b.body.Close()
+}
+
+func (b *body) release() {
+	if b.r != nil {
+		b.pool.put(b.r)
+		b.r = nil
+	}
+}
@@ -0,0 +1,83 @@
+package httpzlib
+
+import (
+	"net/http"
+	"runtime"
+)
+
+// acceptEncoding is the Accept-Encoding header sent by a Transport
+const acceptEncoding = "deflate, gzip"
+
+// TransportOptions configures the Transport returned by NewTransport.
+type TransportOptions struct {
+	// MaxBodySize limits the size of decompressed response bodies; reading past it fails with ErrBodyTooLarge.
+	// The zero value means DefaultMaxBodySize, a negative value means no limit.
+	MaxBodySize int64
+	// PoolSize is the number of idle native streams kept for each content coding.
+	// The zero value means runtime.GOMAXPROCS(0).
+	PoolSize int
+}
+
+func (o TransportOptions) validate() (TransportOptions, error) {
+	if o.MaxBodySize == 0 {
+		o.MaxBodySize = DefaultMaxBodySize
+	}
+	if o.PoolSize == 0 {
+		o.PoolSize = runtime.GOMAXPROCS(0)
+	}
+	if o.PoolSize < 0 {
+		return o, errInvalidPoolSize
+	}
+	return o, nil
+}
+
+// Transport is an http.RoundTripper asking for deflate or gzip compressed responses and decompressing them
+// with native zlib. Responses are decompressed while the body is read and the native stream is returned to a pool
+// once the body has been read completely or closed.
+// Requests that set Accept-Encoding themselves are left alone, like net/http does.
+type Transport struct {
+	base    http.RoundTripper
+	opts    TransportOptions
+	readers *readerPool
+}
+
+// NewTransport returns a new Transport sending requests with base, which is http.DefaultTransport if nil,
+// configured by the given options, which are validated.
+func NewTransport(base http.RoundTripper, opts TransportOptions) (*Transport, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, opts: opts, readers: newReaderPool(opts.PoolSize)}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+// Decompressed responses have the Content-Encoding and Content-Length headers removed and Uncompressed set.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// ranges of a compressed representation cannot be decompressed on their own
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the request
+	r := req.Clone(req.Context())
+	r.Header.Set("Accept-Encoding", acceptEncoding)
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := contentEncoding(resp.Header["Content-Encoding"])
+	if encoding == "" || resp.Body == nil || resp.Body == http.NoBody || req.Method == http.MethodHead {
+		return resp, nil
+	}
+	resp.Body = &body{encoding: encoding, body: resp.Body, pool: t.readers, limit: t.opts.MaxBodySize}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
@@ -0,0 +1,186 @@
+package httpzlib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newEncodingServer serves testBody in the content coding given by the path
+func newEncodingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if act := r.Header.Get("Accept-Encoding"); act != acceptEncoding {
+			t.Errorf("wrong Accept-Encoding %q", act)
+		}
+
+		var zw io.WriteCloser
+		switch r.URL.Path {
+		case "/gzip":
+			zw = gzip.NewWriter(w)
+		case "/deflate":
+			zw = zlib.NewWriter(w)
+		case "/raw":
+			zw, _ = flate.NewWriter(w, flate.DefaultCompression)
+		case "/identity":
+			w.Write(testBody)
+			return
+		}
+		encoding := r.URL.Path[1:]
+		if encoding == "raw" {
+			encoding = "deflate"
+		}
+		w.Header().Set("Content-Encoding", encoding)
+		zw.Write(testBody)
+		zw.Close()
+	}))
+}
+
+func newTestClient(t *testing.T, opts TransportOptions) *http.Client {
+	t.Helper()
+
+	transport, err := NewTransport(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: transport}
+}
+
+func TestTransport(t *testing.T) {
+	server := newEncodingServer(t)
+	defer server.Close()
+	client := newTestClient(t, TransportOptions{PoolSize: 1})
+
+	// several times to reuse pooled streams
+	for i := 0; i < 3; i++ {
+		for _, path := range []string{"/gzip", "/deflate", "/raw", "/identity"} {
+			resp, err := client.Get(server.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			act, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+			resp.Body.Close()
+
+			if !bytes.Equal(act, testBody) {
+				t.Errorf("%s: decompressed body does not match", path)
+			}
+			if path != "/identity" && (resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed || resp.ContentLength != -1) {
+				t.Errorf("%s: response still looks compressed: %v", path, resp.Header)
+			}
+		}
+	}
+}
+
+func TestTransport_MaxBodySize(t *testing.T) {
+	server := newEncodingServer(t)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		size int64
+		err  error
+	}{
+		{int64(len(testBody)), nil},
+		{int64(len(testBody)) - 1, ErrBodyTooLarge},
+		{-1, nil},
+	} {
+		client := newTestClient(t, TransportOptions{MaxBodySize: tc.size})
+		for _, path := range []string{"/gzip", "/deflate"} {
+			resp, err := client.Get(server.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			act, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s, %d: want %v; got %v", path, tc.size, tc.err, err)
+			}
+			if tc.err != nil && int64(len(act)) != tc.size {
+				t.Errorf("%s, %d: want %d bytes up to the limit; got %d", path, tc.size, tc.size, len(act))
+			}
+		}
+	}
+}
+
+func TestTransport_Passthrough(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write(testBody)
+		zw.Close()
+	}))
+	defer server.Close()
+	client := newTestClient(t, TransportOptions{})
+
+	// the caller asking for an encoding decompresses it itself
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatal("the response should be left compressed")
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act, err := ioutil.ReadAll(zr); err != nil || !bytes.Equal(act, testBody) {
+		t.Errorf("decompressed body does not match: %v", err)
+	}
+}
+
+func TestTransport_Handler(t *testing.T) {
+	h := newTestHandler(t, Options{Level: 6}, func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(b)
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	client := newTestClient(t, TransportOptions{})
+
+	// a request compressed by hand and its response compressed by the Handler
+	b := &bytes.Buffer{}
+	zw := zlib.NewWriter(b)
+	zw.Write(testBody)
+	zw.Close()
+	req, _ := http.NewRequest(http.MethodPost, server.URL, b)
+	req.Header.Set("Content-Encoding", "deflate")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	act, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(act, testBody) || !resp.Uncompressed {
+		t.Error("the round trip through Handler and Transport does not match")
+	}
+}
+
+func TestTransport_Invalid(t *testing.T) {
+	if _, err := NewTransport(nil, TransportOptions{PoolSize: -1}); err != errInvalidPoolSize {
+		t.Errorf("want %v; got %v", errInvalidPoolSize, err)
+	}
+}
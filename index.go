@@ -0,0 +1,271 @@
+package zlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+const (
+	// DefaultSpan is the default distance in uncompressed bytes between the access points of an Index
+	DefaultSpan = 1 << 20
+
+	indexBufferSize = 32 * 1024
+	maxWindowSize   = 1 << maxWindowBits
+
+	indexVersion = 1
+)
+
+var indexMagic = [4]byte{'Z', 'I', 'D', 'X'}
+
+// AccessPoint is a position in a compressed stream at which decompression can be started anew.
+type AccessPoint struct {
+	// Out is the offset in the uncompressed data.
+	Out int64
+	// In is the offset of the first complete byte of the access point in the compressed data.
+	In int64
+	// Bits is the number of bits (0..7) of the byte before In that belong to the access point.
+	Bits int
+	// Window holds the last up to 32 KiB of uncompressed data before Out.
+	Window []byte
+}
+
+// Index holds the access points of a zlib, gzip or raw DEFLATE stream, which allow random access to its
+// uncompressed data by a SeekableReader. Every access point keeps a window of up to 32 KiB.
+// An Index can be stored with WriteTo or MarshalBinary and loaded with ReadFrom or UnmarshalBinary.
+// The binary format is stable.
+type Index struct {
+	// Points are the access points in ascending order.
+	Points []AccessPoint
+	// Size is the size of the uncompressed data.
+	Size int64
+}
+
+// BuildIndex decompresses the zlib, gzip or raw DEFLATE stream read from r in one pass and records an access point
+// about every span uncompressed bytes. The format is detected like by NewAutoReader; only the first stream or gzip
+// member is indexed. A span <= 0 selects DefaultSpan. Smaller spans speed up random access at the cost of memory,
+// as every access point keeps a window of up to 32 KiB.
+func BuildIndex(r io.Reader, span int64) (*Index, error) {
+	if span <= 0 {
+		span = DefaultSpan
+	}
+
+	buf := make([]byte, indexBufferSize)
+	in, eof, err := readIndexInput(r, buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(in) == 0 {
+		return nil, errNoInput
+	}
+
+	windowBits := defaultWindowBits + native.AutoWindowOffset
+	if detectContainer(in) == ContainerRaw {
+		windowBits = rawWindowBits
+	}
+	c, err := native.NewDecompressorWindow(windowBits, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	out := make([]byte, indexBufferSize)
+	idx := &Index{}
+	if windowBits == rawWindowBits {
+		// zlib and gzip streams report the access point after their header, while raw streams start with it
+		idx.Points = append(idx.Points, AccessPoint{})
+	}
+	var totalIn, totalOut, last int64
+	for {
+		if len(in) == 0 && !eof {
+			if in, eof, err = readIndexInput(r, buf); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// inflate may only report the end of the stream once called again after the last block
+		consumed, produced, end, err := c.DecompressBlock(in, out)
+		if err != nil {
+			return nil, err
+		}
+		in = in[consumed:]
+		totalIn += int64(consumed)
+		totalOut += int64(produced)
+
+		if end {
+			idx.Size = totalOut
+			return idx, nil
+		}
+		if consumed == 0 && produced == 0 && eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		boundary, bits := c.BlockBoundary()
+		if !boundary || (len(idx.Points) != 0 && totalOut-last < span) {
+			continue
+		}
+		window, err := c.Dictionary()
+		if err != nil {
+			return nil, err
+		}
+		idx.Points = append(idx.Points, AccessPoint{Out: totalOut, In: totalIn, Bits: bits, Window: window})
+		last = totalOut
+	}
+}
+
+// readIndexInput fills buf from r and reports whether r is exhausted
+func readIndexInput(r io.Reader, buf []byte) ([]byte, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return buf[:n], true, nil
+	}
+	return buf[:n], false, err
+}
+
+// point returns the last access point at or before the uncompressed offset off
+func (idx *Index) point(off int64) *AccessPoint {
+	i := sort.Search(len(idx.Points), func(i int) bool {
+		return idx.Points[i].Out > off
+	})
+	if i == 0 {
+		return nil
+	}
+	return &idx.Points[i-1]
+}
+
+// MarshalBinary encodes the Index in its binary format.
+func (idx *Index) MarshalBinary() ([]byte, error) {
+	b := &bytes.Buffer{}
+	if _, err := idx.WriteTo(b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an Index in the binary format written by MarshalBinary or WriteTo.
+func (idx *Index) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if _, err := idx.ReadFrom(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return errInvalidIndex
+	}
+	return nil
+}
+
+// WriteTo writes the Index in its binary format to w, which is, in big endian byte order:
+// the magic "ZIDX", a version byte (1), the uncompressed size (uint64) and the number of access points (uint32),
+// followed by every access point consisting of Out (uint64), In (uint64), Bits (uint8),
+// the length of the window (uint32) and the window itself.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	if err := idx.validate(); err != nil {
+		return 0, err
+	}
+
+	b := &bytes.Buffer{}
+	b.Write(indexMagic[:])
+	b.WriteByte(indexVersion)
+	binary.Write(b, binary.BigEndian, uint64(idx.Size))
+	binary.Write(b, binary.BigEndian, uint32(len(idx.Points)))
+	for _, p := range idx.Points {
+		binary.Write(b, binary.BigEndian, uint64(p.Out))
+		binary.Write(b, binary.BigEndian, uint64(p.In))
+		b.WriteByte(byte(p.Bits))
+		binary.Write(b, binary.BigEndian, uint32(len(p.Window)))
+		b.Write(p.Window)
+	}
+	return b.WriteTo(w)
+}
+
+// ReadFrom reads an Index in the binary format written by WriteTo from r, replacing the contents of idx.
+func (idx *Index) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	read := func(v interface{}) error {
+		err := binary.Read(cr, binary.BigEndian, v)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	var header struct {
+		Magic   [4]byte
+		Version uint8
+		Size    uint64
+		Count   uint32
+	}
+	if err := read(&header); err != nil {
+		return cr.n, err
+	}
+	if header.Magic != indexMagic || header.Version != indexVersion {
+		return cr.n, errInvalidIndex
+	}
+
+	loaded := Index{Size: int64(header.Size)}
+	for i := uint32(0); i < header.Count; i++ {
+		var point struct {
+			Out, In    uint64
+			Bits       uint8
+			WindowSize uint32
+		}
+		if err := read(&point); err != nil {
+			return cr.n, err
+		}
+		if point.WindowSize > maxWindowSize {
+			return cr.n, errInvalidIndex
+		}
+		window := make([]byte, point.WindowSize)
+		if _, err := io.ReadFull(cr, window); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return cr.n, err
+		}
+		loaded.Points = append(loaded.Points, AccessPoint{
+			Out:    int64(point.Out),
+			In:     int64(point.In),
+			Bits:   int(point.Bits),
+			Window: window,
+		})
+	}
+
+	if err := loaded.validate(); err != nil {
+		return cr.n, err
+	}
+	*idx = loaded
+	return cr.n, nil
+}
+
+func (idx *Index) validate() error {
+	if len(idx.Points) == 0 || idx.Points[0].Out != 0 || idx.Size < 0 {
+		return errInvalidIndex
+	}
+	for i, p := range idx.Points {
+		if p.Out < 0 || p.Out > idx.Size || p.In < 0 || p.Bits < 0 || p.Bits > 7 || (p.Bits > 0 && p.In == 0) {
+			return errInvalidIndex
+		}
+		if len(p.Window) > maxWindowSize {
+			return errInvalidIndex
+		}
+		if i > 0 && (p.Out < idx.Points[i-1].Out || p.In < idx.Points[i-1].In) {
+			return errInvalidIndex
+		}
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,202 @@
+package zlib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+const indexTestSpan = 64 * 1024
+
+func makeIndexTestData() []byte {
+	words := [][]byte{[]byte("zlib "), []byte("index "), []byte("access "), []byte("point "), []byte("window "), []byte("\n")}
+	rnd := rand.New(rand.NewSource(1))
+
+	b := &bytes.Buffer{}
+	for b.Len() < 1<<20 {
+		b.Write(words[rnd.Intn(len(words))])
+		b.WriteByte(byte(rnd.Intn(256)))
+	}
+	return b.Bytes()
+}
+
+func compressIndexTestData(t *testing.T, container Container, data []byte) []byte {
+	b := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch container {
+	case ContainerZlib:
+		w = zlib.NewWriter(b)
+	case ContainerGzip:
+		w = gzip.NewWriter(b)
+	case ContainerRaw:
+		w, _ = flate.NewWriter(b, flate.DefaultCompression)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return b.Bytes()
+}
+
+// UNIT TESTS
+
+func TestBuildIndex(t *testing.T) {
+	data := makeIndexTestData()
+
+	for _, container := range []Container{ContainerZlib, ContainerGzip, ContainerRaw} {
+		compressed := compressIndexTestData(t, container, data)
+
+		idx, err := BuildIndex(bytes.NewReader(compressed), indexTestSpan)
+		if err != nil {
+			t.Fatalf("%v: %v", container, err)
+		}
+		if idx.Size != int64(len(data)) {
+			t.Errorf("%v: wrong size: want %d; got %d", container, len(data), idx.Size)
+		}
+		if len(idx.Points) < 2 {
+			t.Errorf("%v: too few access points: %d", container, len(idx.Points))
+		}
+		for i, p := range idx.Points {
+			if i > 0 && p.Out-idx.Points[i-1].Out < indexTestSpan {
+				t.Errorf("%v: access points closer than the span: %d and %d", container, idx.Points[i-1].Out, p.Out)
+			}
+			if want := data[max64(0, p.Out-maxWindowSize):p.Out]; !bytes.Equal(want, p.Window) {
+				t.Errorf("%v: wrong window at %d", container, p.Out)
+			}
+		}
+
+		testSeekableReader(t, container, idx, compressed, data)
+	}
+}
+
+func testSeekableReader(t *testing.T, container Container, idx *Index, compressed, data []byte) {
+	s, err := NewSeekableReader(bytes.NewReader(compressed), idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		off := rnd.Int63n(int64(len(data)))
+		p := make([]byte, rnd.Intn(3*indexBufferSize)+1)
+
+		n, err := s.ReadAt(p, off)
+		want := data[off:min64(int64(len(data)), off+int64(len(p)))]
+		if n < len(p) && err != io.EOF || n == len(p) && err != nil {
+			t.Fatalf("%v: unexpected error at %d: %v", container, off, err)
+		}
+		if !bytes.Equal(want, p[:n]) {
+			t.Fatalf("%v: wrong data at %d", container, off)
+		}
+	}
+
+	if _, err := s.Seek(int64(len(data))/3, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	act, err := ioutil.ReadAll(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sliceEquals(t, data[len(data)/3:], act)
+
+	if pos, _ := s.Seek(-10, io.SeekEnd); pos != int64(len(data))-10 {
+		t.Errorf("%v: wrong position: %d", container, pos)
+	}
+	if _, err := s.Seek(-1, io.SeekStart); err != errNegativeOffset {
+		t.Errorf("expected %v; got %v", errNegativeOffset, err)
+	}
+	if n, err := s.ReadAt(make([]byte, 1), int64(len(data))); n != 0 || err != io.EOF {
+		t.Errorf("expected EOF; got %d, %v", n, err)
+	}
+}
+
+func TestIndex_Binary(t *testing.T) {
+	data := makeIndexTestData()
+	compressed := compressIndexTestData(t, ContainerZlib, data)
+
+	idx, err := BuildIndex(bytes.NewReader(compressed), indexTestSpan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := idx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte("ZIDX\x01")) {
+		t.Errorf("unexpected header: %q", b[:5])
+	}
+
+	loaded := &Index{}
+	if err := loaded.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Size != idx.Size || len(loaded.Points) != len(idx.Points) {
+		t.Fatalf("index changed: want %d points of %d bytes; got %d points of %d bytes",
+			len(idx.Points), idx.Size, len(loaded.Points), loaded.Size)
+	}
+	for i, p := range loaded.Points {
+		q := idx.Points[i]
+		if p.Out != q.Out || p.In != q.In || p.Bits != q.Bits || !bytes.Equal(p.Window, q.Window) {
+			t.Errorf("access point %d changed", i)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := idx.WriteTo(buf)
+	if err != nil || n != int64(len(b)) || !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("WriteTo differs from MarshalBinary: %d bytes, %v", n, err)
+	}
+	fromReader := &Index{}
+	if n, err := fromReader.ReadFrom(buf); err != nil || n != int64(len(b)) {
+		t.Errorf("ReadFrom failed after %d bytes: %v", n, err)
+	}
+
+	testSeekableReader(t, ContainerZlib, loaded, compressed, data)
+}
+
+func TestIndex_Invalid(t *testing.T) {
+	data := makeIndexTestData()
+	idx, err := BuildIndex(bytes.NewReader(compressIndexTestData(t, ContainerZlib, data)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := idx.MarshalBinary()
+
+	if err := (&Index{}).UnmarshalBinary(b[:len(b)-1]); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected %v; got %v", io.ErrUnexpectedEOF, err)
+	}
+	if err := (&Index{}).UnmarshalBinary(append(b, 0)); err != errInvalidIndex {
+		t.Errorf("expected %v; got %v", errInvalidIndex, err)
+	}
+	corrupted := append([]byte{}, b...)
+	corrupted[0] = 'X'
+	if err := (&Index{}).UnmarshalBinary(corrupted); err != errInvalidIndex {
+		t.Errorf("expected %v; got %v", errInvalidIndex, err)
+	}
+	if _, err := NewSeekableReader(bytes.NewReader(nil), &Index{}); err != errInvalidIndex {
+		t.Errorf("expected %v; got %v", errInvalidIndex, err)
+	}
+	if _, err := BuildIndex(bytes.NewReader(nil), 0); err != errNoInput {
+		t.Errorf("expected %v; got %v", errNoInput, err)
+	}
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+package zlib
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+const (
+	zlibHeaderSize  = 2
+	zlibTrailerSize = 4
+	zlibDeflated    = 8
+	zlibPresetDict  = 0x20
+)
+
+// InflateTo decompresses the zlib stream read from src and writes the decompressed data to dst.
+// It is built on zlib's inflateBack, which decompresses straight into a 32 KiB window that is written to dst
+// whenever it is full, avoiding the intermediate buffers of Reader. This makes it the fastest way to decompress
+// large streams, such as local files, to an io.Writer.
+// It returns the number of bytes written to dst. Input following the stream may have been consumed from src.
+// Streams requiring a preset dictionary are not supported and result in a *DictionaryError.
+func InflateTo(dst io.Writer, src io.Reader) (int64, error) {
+	var header [zlibHeaderSize]byte
+	if _, err := io.ReadFull(src, header[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if header[0]&0x0f != zlibDeflated || header[0]>>4 > maxWindowBits-8 || binary.BigEndian.Uint16(header[:])%31 != 0 {
+		return 0, errHeader
+	}
+	if header[1]&zlibPresetDict != 0 {
+		var id [4]byte
+		if _, err := io.ReadFull(src, id[:]); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		return 0, &DictionaryError{ID: binary.BigEndian.Uint32(id[:])}
+	}
+
+	n, adler, rest, err := native.InflateBack(dst, src)
+	if err != nil {
+		return n, err
+	}
+
+	var trailer [zlibTrailerSize]byte
+	copied := copy(trailer[:], rest)
+	if _, err := io.ReadFull(src, trailer[copied:]); err != nil {
+		return n, unexpectedEOF(err)
+	}
+	if binary.BigEndian.Uint32(trailer[:]) != adler {
+		return n, errChecksum
+	}
+	return n, nil
+}
+
+// InflateRawTo performs like InflateTo but decompresses raw DEFLATE (RFC 1951) data without the zlib header and trailer.
+func InflateRawTo(dst io.Writer, src io.Reader) (int64, error) {
+	n, _, _, err := native.InflateBack(dst, src)
+	return n, err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
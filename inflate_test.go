@@ -0,0 +1,101 @@
+package zlib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// UNIT TESTS
+
+func TestInflateTo(t *testing.T) {
+	data := xWords(1 << 20)
+
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(data)
+	w.Close()
+	compressed := b.Bytes()
+
+	for _, src := range []io.Reader{bytes.NewReader(compressed), iotest.OneByteReader(bytes.NewReader(compressed))} {
+		act := &bytes.Buffer{}
+		n, err := InflateTo(act, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(data)) {
+			t.Errorf("wrong number of bytes written: want %d; got %d", len(data), n)
+		}
+		sliceEquals(t, data, act.Bytes())
+	}
+}
+
+func TestInflateRawTo(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w, _ := flate.NewWriter(b, flate.BestCompression)
+	w.Write(longString)
+	w.Close()
+
+	act := &bytes.Buffer{}
+	if _, err := InflateRawTo(act, b); err != nil {
+		t.Fatal(err)
+	}
+	sliceEquals(t, longString, act.Bytes())
+}
+
+func TestInflateTo_Invalid(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(shortString)
+	w.Close()
+	compressed := b.Bytes()
+
+	corrupted := append([]byte{}, compressed...)
+	corrupted[len(corrupted)-1]++
+	if _, err := InflateTo(&bytes.Buffer{}, bytes.NewReader(corrupted)); !errors.Is(err, ErrData) {
+		t.Errorf("expected %v; got %v", ErrData, err)
+	}
+
+	if _, err := InflateTo(&bytes.Buffer{}, bytes.NewReader(compressed[:len(compressed)-6])); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected %v; got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	if _, err := InflateTo(&bytes.Buffer{}, bytes.NewReader([]byte{0x78, 0x00})); !errors.Is(err, ErrData) {
+		t.Errorf("expected %v; got %v", ErrData, err)
+	}
+
+	b.Reset()
+	dw, _ := zlib.NewWriterLevelDict(b, zlib.DefaultCompression, testDict)
+	dw.Write(shortString)
+	dw.Close()
+	if _, err := InflateTo(&bytes.Buffer{}, b); !errors.Is(err, ErrDictionary) {
+		t.Errorf("expected %v; got %v", ErrDictionary, err)
+	}
+}
+
+type failingWriter struct{}
+
+var errFailingWriter = errors.New("failing writer")
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errFailingWriter
+}
+
+func TestInflateTo_WriteError(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(longString)
+	w.Close()
+
+	if _, err := InflateTo(failingWriter{}, b); err != errFailingWriter {
+		t.Errorf("expected %v; got %v", errFailingWriter, err)
+	}
+}
@@ -0,0 +1,168 @@
+package zlib
+
+import (
+	"io"
+	"math"
+)
+
+// DefaultRatioThreshold is the default number of decompressed bytes up to which Limits.MaxRatio is not enforced
+const DefaultRatioThreshold = 1 << 20
+
+const (
+	// assumedCompressionFactor is the initial guess of the ratio by ReadBuffer
+	assumedCompressionFactor = 7
+	minGrowth                = 8192
+	maxInt                   = int(^uint(0) >> 1)
+)
+
+// Limits restrict the decompressed output of a Reader to protect against decompression bombs.
+// No more output than allowed is ever allocated or buffered by the Reader.
+type Limits struct {
+	// MaxOutput is the maximum number of decompressed bytes since the Reader was created or Reset.
+	// Exceeding it fails with ErrOutputLimit. The zero value means no limit.
+	MaxOutput int64
+	// MaxRatio is the maximum ratio of decompressed bytes to the compressed bytes read by Read or passed to
+	// ReadBuffer, like 100 for 100:1. Exceeding it fails with ErrRatioLimit. The zero value means no limit.
+	// The ratio of DEFLATE is at most about 1032:1.
+	MaxRatio int64
+	// RatioThreshold is the number of decompressed bytes up to which MaxRatio is not enforced, so that streams
+	// starting with highly compressible data can be read. The zero value means DefaultRatioThreshold.
+	RatioThreshold int64
+}
+
+func (l Limits) validate() error {
+	if l.MaxOutput < 0 || l.MaxRatio < 0 || l.RatioThreshold < 0 {
+		return errInvalidLimits
+	}
+	return nil
+}
+
+func (l Limits) ratioThreshold() int64 {
+	if l.RatioThreshold == 0 {
+		return DefaultRatioThreshold
+	}
+	return l.RatioThreshold
+}
+
+func (l Limits) enabled() bool {
+	return l.MaxOutput != 0 || l.MaxRatio != 0
+}
+
+// space returns how many bytes may be decompressed (up to n) after out bytes have been decompressed from
+// in bytes of input, and the error to fail with if the stream exceeds that
+func (l Limits) space(in, out int64, n int) (int, error) {
+	space, err := int64(n), error(nil)
+	if l.MaxOutput != 0 && l.MaxOutput-out < space {
+		space, err = l.MaxOutput-out, ErrOutputLimit
+	}
+	if l.MaxRatio != 0 && in <= math.MaxInt64/l.MaxRatio {
+		allowed := l.MaxRatio * in
+		if threshold := l.ratioThreshold(); allowed < threshold {
+			allowed = threshold
+		}
+		if allowed-out < space {
+			space, err = allowed-out, ErrRatioLimit
+		}
+	}
+	if space < 0 {
+		space = 0
+	}
+	return int(space), err
+}
+
+// SetLimits restricts the decompressed output of Read and ReadBuffer. The limits are kept across Reset.
+// Read returns the data up to a limit together with ErrOutputLimit or ErrRatioLimit once the stream
+// would exceed it; the Reader has to be Reset afterwards. With limits, Read never buffers more output
+// than fits into p, and ReadBuffer never allocates more than allowed, even if out is nil.
+func (r *Reader) SetLimits(limits Limits) error {
+	if err := limits.validate(); err != nil {
+		return err
+	}
+	r.limits = limits
+	return nil
+}
+
+// decompress decompresses in to p, or into a larger slice if there are no limits and the Reader does not recover.
+// A recovering Reader needs the progress made up to corrupted data, which only Inflate reports.
+func (r *Reader) decompress(in, p []byte) (bool, int, []byte, error) {
+	if !r.limits.enabled() {
+		if r.recovery != nil {
+			consumed, produced, end, err := r.decompressor.Inflate(in, p)
+			return end, consumed, p[:produced], err
+		}
+		return r.decompressor.DecompressStream(in, p)
+	}
+
+	space, limitErr := r.limits.space(r.offset+int64(len(in)), r.outOffset, len(p))
+	consumed, produced, end, err := r.decompressor.Inflate(in, p[:space])
+	if err != nil || end || space == len(p) || produced < space {
+		return end, consumed, p[:produced], err
+	}
+
+	// the output has been cut short by a limit, which is only exceeded if the stream continues
+	n, m, end, err := r.decompressor.Inflate(in[consumed:], make([]byte, 1))
+	consumed += n
+	if m != 0 {
+		err = limitErr
+	}
+	return end, consumed, p[:produced], err
+}
+
+// readBufferLimited decompresses a whole stream like ReadBuffer, but grows out only up to the limits
+func (r *Reader) readBufferLimited(compressed, out []byte) (int, []byte, error) {
+	n, out, err := r.inflateLimited(compressed, out)
+	if rerr := r.decompressor.Reset(); err == nil {
+		err = rerr
+	}
+	return n, out, err
+}
+
+func (r *Reader) inflateLimited(compressed, out []byte) (int, []byte, error) {
+	max, limitErr := r.limits.space(int64(len(compressed)), 0, maxInt)
+	if out == nil {
+		out = make([]byte, 0, minInt(len(compressed)*assumedCompressionFactor, max))
+	}
+	out = out[:0:minInt(cap(out), max)]
+
+	consumed := 0
+	for {
+		if len(out) == cap(out) && cap(out) < max {
+			grown := make([]byte, len(out), minInt(2*cap(out)+minGrowth, max))
+			out = grown[:copy(grown, out)]
+		}
+
+		n, m, end, err := r.decompressor.Inflate(compressed[consumed:], out[len(out):cap(out)])
+		consumed += n
+		out = out[:len(out)+m]
+		if err != nil {
+			return consumed, out, err
+		}
+		if end {
+			return consumed, out, nil
+		}
+		if len(out) < cap(out) {
+			return consumed, out, io.ErrUnexpectedEOF
+		}
+
+		if cap(out) >= max {
+			n, m, end, err := r.decompressor.Inflate(compressed[consumed:], make([]byte, 1))
+			consumed += n
+			switch {
+			case m != 0:
+				return consumed, out, limitErr
+			case err != nil:
+				return consumed, out, err
+			case !end:
+				return consumed, out, io.ErrUnexpectedEOF
+			}
+			return consumed, out, nil
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
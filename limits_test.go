@@ -0,0 +1,195 @@
+package zlib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+// bomb returns n zeros compressed by compress/zlib
+func bomb(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := &bytes.Buffer{}
+	w, _ := zlib.NewWriterLevel(b, zlib.BestCompression)
+	w.Write(make([]byte, n))
+	w.Close()
+	return b.Bytes()
+}
+
+func newLimitedReader(t *testing.T, compressed []byte, limits Limits) *Reader {
+	t.Helper()
+
+	r, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetLimits(limits); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReader_OutputLimit(t *testing.T) {
+	const n = 100 << 20
+	compressed := bomb(t, n)
+
+	for _, tc := range []struct {
+		limit int64
+		err   error
+	}{
+		{1 << 20, ErrOutputLimit},
+		{n - 1, ErrOutputLimit},
+		{n, nil},
+		{n + 1, nil},
+	} {
+		r := newLimitedReader(t, compressed, Limits{MaxOutput: tc.limit})
+		act, err := io.Copy(ioutil.Discard, r)
+		if err != tc.err {
+			t.Errorf("Read, %d: want %v; got %v", tc.limit, tc.err, err)
+		}
+		if exp := minInt(n, int(tc.limit)); act != int64(exp) {
+			t.Errorf("Read, %d: want %d bytes; got %d", tc.limit, exp, act)
+		}
+
+		r.Close()
+
+		r = newLimitedReader(t, compressed, Limits{MaxOutput: tc.limit})
+		_, out, err := r.ReadBuffer(compressed, nil)
+		if err != tc.err {
+			t.Errorf("ReadBuffer, %d: want %v; got %v", tc.limit, tc.err, err)
+		}
+		if int64(cap(out)) > tc.limit {
+			t.Errorf("ReadBuffer, %d: allocated %d bytes past the limit", tc.limit, cap(out))
+		}
+		if tc.err == nil && len(out) != n {
+			t.Errorf("ReadBuffer, %d: want %d bytes; got %d", tc.limit, n, len(out))
+		}
+		r.Close()
+	}
+}
+
+func TestReader_RatioLimit(t *testing.T) {
+	compressed := bomb(t, 10<<20)
+	ratio := int64(10<<20) / int64(len(compressed))
+
+	for _, tc := range []struct {
+		ratio int64
+		err   error
+	}{
+		{100, ErrRatioLimit},
+		{ratio - 1, ErrRatioLimit},
+		{ratio + 1, nil},
+	} {
+		r := newLimitedReader(t, compressed, Limits{MaxRatio: tc.ratio})
+		if _, err := io.Copy(ioutil.Discard, r); err != tc.err {
+			t.Errorf("Read, %d: want %v; got %v", tc.ratio, tc.err, err)
+		}
+
+		r.Close()
+
+		r = newLimitedReader(t, compressed, Limits{MaxRatio: tc.ratio})
+		_, out, err := r.ReadBuffer(compressed, nil)
+		if err != tc.err {
+			t.Errorf("ReadBuffer, %d: want %v; got %v", tc.ratio, tc.err, err)
+		}
+		max := tc.ratio * int64(len(compressed))
+		if max < DefaultRatioThreshold {
+			max = DefaultRatioThreshold
+		}
+		if int64(cap(out)) > max {
+			t.Errorf("ReadBuffer, %d: allocated %d bytes past the limit of %d", tc.ratio, cap(out), max)
+		}
+		r.Close()
+	}
+
+	// random data does not compress, so a ratio of 1 is fine
+	random := make([]byte, 1<<16)
+	rand.Read(random)
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(random)
+	w.Close()
+	r := newLimitedReader(t, b.Bytes(), Limits{MaxRatio: 1})
+	defer r.Close()
+	if act, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(act, random) {
+		t.Errorf("decompressed data does not match: %v", err)
+	}
+}
+
+func TestReader_RatioThreshold(t *testing.T) {
+	// highly compressible data followed by data that does not compress stays well below a ratio of 10 overall
+	data := make([]byte, 512<<10, 512<<10+1<<20)
+	random := make([]byte, 1<<20)
+	rand.Read(random)
+	data = append(data, random...)
+
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(data)
+	w.Close()
+
+	for _, tc := range []struct {
+		threshold int64
+		err       error
+	}{
+		{0, nil},
+		{1, ErrRatioLimit},
+	} {
+		// the input arrives in pieces, like from the network
+		r, err := NewReader(iotest.OneByteReader(bytes.NewReader(b.Bytes())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.SetLimits(Limits{MaxRatio: 10, RatioThreshold: tc.threshold}); err != nil {
+			t.Fatal(err)
+		}
+		act, err := ioutil.ReadAll(r)
+		if err != tc.err {
+			t.Errorf("%d: want %v; got %v", tc.threshold, tc.err, err)
+		}
+		if err == nil && !bytes.Equal(act, data) {
+			t.Errorf("%d: decompressed data does not match", tc.threshold)
+		}
+		r.Close()
+	}
+}
+
+func TestReader_LimitsReadBuffer(t *testing.T) {
+	compressed := bomb(t, 1<<20)
+	r := newLimitedReader(t, compressed, Limits{MaxOutput: 2 << 20})
+	defer r.Close()
+
+	// the stream is reset after each call
+	for i := 0; i < 2; i++ {
+		_, out, err := r.ReadBuffer(compressed, nil)
+		if err != nil || !bytes.Equal(out, make([]byte, 1<<20)) {
+			t.Fatalf("decompressed data does not match: %v", err)
+		}
+	}
+
+	if _, _, err := r.ReadBuffer(compressed[:len(compressed)/2], nil); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated: want %v; got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// a provided buffer is used up to the limit
+	_, out, err := r.ReadBuffer(compressed, make([]byte, 0, 4<<20))
+	if err != nil || len(out) != 1<<20 {
+		t.Errorf("want %d bytes; got %d, %v", 1<<20, len(out), err)
+	}
+}
+
+func TestReader_InvalidLimits(t *testing.T) {
+	r := newLimitedReader(t, bomb(t, 1), Limits{})
+	defer r.Close()
+
+	for _, limits := range []Limits{{MaxOutput: -1}, {MaxRatio: -1}, {RatioThreshold: -1}} {
+		if err := r.SetLimits(limits); err != errInvalidLimits {
+			t.Errorf("%+v: want %v; got %v", limits, errInvalidLimits, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package zlib
+
+import "github.com/4kills/go-zlib/native"
+
+// NativeMemory returns the number of bytes zlib has currently allocated for the Writer, or 0 once it has been closed.
+func (zw *Writer) NativeMemory() int {
+	if zw.err != nil {
+		return 0
+	}
+	return zw.compressor.Memory()
+}
+
+// NativeMemory returns the number of bytes zlib has currently allocated for the Reader, or 0 once it has been closed.
+// The window is allocated once a Read returns before the end of a stream.
+func (r *Reader) NativeMemory() int {
+	if r.err != nil {
+		return 0
+	}
+	return r.decompressor.Memory()
+}
+
+// NativeMemory returns the number of bytes zlib has currently allocated for all Writers and Readers of the process,
+// including those of the subpackages, gzip header buffers and the streams of InflateTo.
+func NativeMemory() int64 {
+	return native.TotalMemory()
+}
+
+// SetMemoryBudget limits the memory zlib may allocate for all Writers and Readers of the process to budget bytes,
+// 0 meaning no limit. Creating a Writer or Reader that would exceed it fails with an error matching ErrMemory
+// instead of allocating. So may Read, as a Reader allocates its window once a Read returns before the end of a stream,
+// as well as InflateTo and setting or parsing gzip headers.
+// Constructors without an error result, like NewWriter, return a Writer or Reader whose methods fail with that error,
+// so prefer those returning errors, like NewWriterLevel, under a budget.
+// Memory allocated before is kept, even if it exceeds a new budget.
+func SetMemoryBudget(budget int64) error {
+	if budget < 0 {
+		return errInvalidBudget
+	}
+	native.SetMemoryBudget(budget)
+	return nil
+}
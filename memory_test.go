@@ -0,0 +1,132 @@
+package zlib
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNativeMemory(t *testing.T) {
+	opts := Options{Level: DefaultCompression}
+	w, err := NewWriterOptions(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp, _ := WriterMemory(opts)
+	act := w.NativeMemory()
+	if act < exp/2 || act > exp*2 {
+		t.Errorf("want about %d bytes; got %d", exp, act)
+	}
+	if NativeMemory() < int64(act) {
+		t.Errorf("the global counter %d does not include the writer's %d bytes", NativeMemory(), act)
+	}
+
+	// the memory of a copy is accounted to the copy
+	clone, err := w.Clone(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.NativeMemory() != act || clone.NativeMemory() != act {
+		t.Errorf("want %d bytes for both the writer and its copy; got %d and %d", act, w.NativeMemory(), clone.NativeMemory())
+	}
+	clone.Close()
+
+	w.Close()
+	if w.NativeMemory() != 0 {
+		t.Errorf("want no memory after Close; got %d", w.NativeMemory())
+	}
+}
+
+// compressedShortString returns shortString compressed
+func compressedShortString() []byte {
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Write(shortString)
+	w.Close()
+	return b.Bytes()
+}
+
+func TestNativeMemory_Reader(t *testing.T) {
+	r, err := NewReader(iotest.OneByteReader(bytes.NewReader(compressedShortString())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// the window is allocated by the first Read returning before the end of the stream
+	before := r.NativeMemory()
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if before == 0 || r.NativeMemory() <= before {
+		t.Errorf("want the memory to grow by the window; got %d and %d bytes", before, r.NativeMemory())
+	}
+}
+
+func TestSetMemoryBudget(t *testing.T) {
+	r, err := NewReader(iotest.OneByteReader(bytes.NewReader(compressedShortString())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := SetMemoryBudget(1); err != nil {
+		t.Fatal(err)
+	}
+	defer SetMemoryBudget(0)
+
+	if w, err := NewWriterLevel(nil, DefaultCompression); w != nil || !errors.Is(err, ErrMemory) {
+		t.Errorf("NewWriterLevel: want nil, %v; got %v, %v", ErrMemory, w, err)
+	}
+	if r, err := NewReader(nil); r != nil || !errors.Is(err, ErrMemory) {
+		t.Errorf("NewReader: want nil, %v; got %v, %v", ErrMemory, r, err)
+	}
+	if _, err := ioutil.ReadAll(r); !errors.Is(err, ErrMemory) {
+		t.Errorf("Read: want %v; got %v", ErrMemory, err)
+	}
+
+	if err := SetMemoryBudget(-1); err != errInvalidBudget {
+		t.Errorf("want %v; got %v", errInvalidBudget, err)
+	}
+}
+
+func TestSetMemoryBudget_NoErrorResult(t *testing.T) {
+	if err := SetMemoryBudget(1); err != nil {
+		t.Fatal(err)
+	}
+	defer SetMemoryBudget(0)
+
+	// constructors without an error result report it on use instead of panicking
+	w := NewWriter(&bytes.Buffer{})
+	if _, err := w.Write(shortString); !errors.Is(err, ErrMemory) {
+		t.Errorf("Write: want %v; got %v", ErrMemory, err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrMemory) {
+		t.Errorf("Close: want %v; got %v", ErrMemory, err)
+	}
+	if w.NativeMemory() != 0 {
+		t.Errorf("want no memory; got %d", w.NativeMemory())
+	}
+
+	r := NewRawReader(bytes.NewReader(shortString))
+	if _, err := r.Read(make([]byte, 10)); !errors.Is(err, ErrMemory) {
+		t.Errorf("Read: want %v; got %v", ErrMemory, err)
+	}
+	if err := r.Close(); !errors.Is(err, ErrMemory) {
+		t.Errorf("Close: want %v; got %v", ErrMemory, err)
+	}
+}
+
+func TestSetMemoryBudget_InflateTo(t *testing.T) {
+	compressed := compressedShortString()
+	if err := SetMemoryBudget(1); err != nil {
+		t.Fatal(err)
+	}
+	defer SetMemoryBudget(0)
+	if _, err := InflateTo(ioutil.Discard, bytes.NewReader(compressed)); !errors.Is(err, ErrMemory) {
+		t.Errorf("want %v; got %v", ErrMemory, err)
+	}
+}
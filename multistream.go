@@ -0,0 +1,37 @@
+package zlib
+
+// StreamInfo describes the position of a stream within concatenated streams read by a Reader.
+type StreamInfo struct {
+	// Index is the number of the stream, starting at 0.
+	Index int
+	// In is the offset of the stream in the compressed input.
+	In int64
+	// Out is the offset of the data of the stream in the decompressed output.
+	Out int64
+}
+
+// Multistream controls whether Read continues with the next stream after the end of a stream, which is
+// the case for data made of back-to-back zlib (or gzip or raw DEFLATE) streams. Input buffered past the end
+// of a stream is used for the next one, and io.EOF is only returned once the input ends at the end of a stream.
+// It is disabled by default, so Read returns io.EOF at the end of the first stream.
+// The stream being read is reported by Stream. ReadBuffer is not affected.
+func (r *Reader) Multistream(ok bool) {
+	r.multistream = ok
+}
+
+// Stream returns the index and offsets of the stream being read, or of the last one if the input has ended.
+func (r *Reader) Stream() StreamInfo {
+	return r.stream
+}
+
+// nextStream prepares the decompressor for the stream following the one that has ended
+func (r *Reader) nextStream() error {
+	r.streamEnded = false
+	r.stream = StreamInfo{Index: r.stream.Index + 1, In: r.offset, Out: r.outOffset}
+	if r.auto {
+		// the format is detected anew, which resets the decompressor as well
+		r.container = ContainerUnknown
+		return nil
+	}
+	return r.decompressor.Reset()
+}
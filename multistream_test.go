@@ -0,0 +1,127 @@
+package zlib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+)
+
+// multistreamTestData returns the streams, their concatenated compressed data and the compressed offset of each
+func multistreamTestData(t *testing.T) ([][]byte, []byte, []int64) {
+	t.Helper()
+
+	streams := [][]byte{shortString, bytes.Repeat([]byte("multistream "), 10000), []byte("x")}
+	b := &bytes.Buffer{}
+	var offsets []int64
+	for _, s := range streams {
+		offsets = append(offsets, int64(b.Len()))
+		w := zlib.NewWriter(b)
+		w.Write(s)
+		w.Close()
+	}
+	return streams, b.Bytes(), offsets
+}
+
+func TestReader_Multistream(t *testing.T) {
+	streams, compressed, offsets := multistreamTestData(t)
+
+	for name, src := range map[string]func() io.Reader{
+		"whole":   func() io.Reader { return bytes.NewReader(compressed) },
+		"onebyte": func() io.Reader { return iotest.OneByteReader(bytes.NewReader(compressed)) },
+	} {
+		r, err := NewReader(src())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Multistream(true)
+
+		// read stream by stream to check the reported positions
+		var out int64
+		for i, s := range streams {
+			act := make([]byte, len(s))
+			if _, err := io.ReadFull(r, act); err != nil {
+				t.Fatalf("%s: stream %d: %v", name, i, err)
+			}
+			if !bytes.Equal(act, s) {
+				t.Errorf("%s: stream %d: decompressed data does not match", name, i)
+			}
+			if act, exp := r.Stream(), (StreamInfo{Index: i, In: offsets[i], Out: out}); act != exp {
+				t.Errorf("%s: want %+v; got %+v", name, exp, act)
+			}
+			out += int64(len(s))
+		}
+
+		if rest, err := ioutil.ReadAll(r); len(rest) != 0 || err != nil {
+			t.Errorf("%s: want no more data; got %d bytes, %v", name, len(rest), err)
+		}
+		if act := r.Stream(); act.Index != len(streams)-1 {
+			t.Errorf("%s: wrong index of the last stream: %d", name, act.Index)
+		}
+		r.Close()
+	}
+}
+
+func TestReader_NoMultistream(t *testing.T) {
+	streams, compressed, _ := multistreamTestData(t)
+
+	r, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(act, streams[0]) {
+		t.Error("only the first stream should be read")
+	}
+}
+
+func TestReader_MultistreamAuto(t *testing.T) {
+	b := &bytes.Buffer{}
+	gw := gzip.NewWriter(b)
+	gw.Write(shortString)
+	gw.Close()
+	zw := zlib.NewWriter(b)
+	zw.Write(shortString)
+	zw.Close()
+
+	r, err := NewAutoReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Multistream(true)
+
+	act, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(act, bytes.Repeat(shortString, 2)) {
+		t.Error("decompressed data does not match")
+	}
+	if r.Container() != ContainerZlib || r.Stream().Index != 1 {
+		t.Errorf("wrong stream: %v, %+v", r.Container(), r.Stream())
+	}
+}
+
+func TestReader_MultistreamTrailingGarbage(t *testing.T) {
+	_, compressed, _ := multistreamTestData(t)
+
+	r, err := NewReader(bytes.NewReader(append(compressed, 0xff, 0xff)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Multistream(true)
+
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Error("expected an error for trailing garbage")
+	}
+}
@@ -0,0 +1,47 @@
+#include "back.h"
+#include "_cgo_export.h"
+
+static unsigned backIn(void* desc, z_const unsigned char** buf) {
+	backStream* b = (backStream*) desc;
+	*buf = b->in;
+	return goBackIn(b->handle, b->in, b->inSize);
+}
+
+static int backOut(void* desc, unsigned char* buf, unsigned len) {
+	backStream* b = (backStream*) desc;
+	return goBackOut(b->handle, buf, len);
+}
+
+void freeBackStream(backStream* b) {
+	if (b == NULL) {
+		return;
+	}
+	freeBytes(b->window);
+	freeBytes(b->in);
+	freeBytes(b);
+}
+
+backStream* newBackStream(int windowBits, unsigned inSize) {
+	backStream* b = (backStream*) allocZeroed(sizeof(backStream));
+	if (b == NULL) {
+		return NULL;
+	}
+	b->strm.zalloc = allocMem;
+	b->strm.zfree = freeAllocated;
+
+	b->window = (unsigned char*) allocBytes(1u << windowBits);
+	b->in = (unsigned char*) allocBytes(inSize);
+	b->inSize = inSize;
+	if (b->window == NULL || b->in == NULL || inflateBackInit(&b->strm, windowBits, b->window) != Z_OK) {
+		freeBackStream(b);
+		return NULL;
+	}
+	return b;
+}
+
+int runInflateBack(backStream* b, uintptr_t handle) {
+	b->handle = handle;
+	b->strm.next_in = Z_NULL;
+	b->strm.avail_in = 0;
+	return inflateBack(&b->strm, backIn, b, backOut, b);
+}
@@ -0,0 +1,135 @@
+package native
+
+/*
+#include "back.h"
+*/
+import "C"
+
+import (
+	"io"
+	"sync"
+	"unsafe"
+)
+
+const backInputSize = 32 * 1024
+
+// backState is the go side of an ongoing inflateBack, which the c callbacks find by its handle
+type backState struct {
+	dst     io.Writer
+	src     io.Reader
+	written int64
+	adler   C.uLong
+	err     error
+}
+
+// backStates maps handles to the states of ongoing inflateBack calls, as go pointers must not be kept by c
+var backStates = struct {
+	sync.Mutex
+	m    map[uintptr]*backState
+	next uintptr
+}{m: make(map[uintptr]*backState)}
+
+func registerBackState(s *backState) uintptr {
+	backStates.Lock()
+	defer backStates.Unlock()
+
+	backStates.next++
+	backStates.m[backStates.next] = s
+	return backStates.next
+}
+
+func lookupBackState(handle uintptr) *backState {
+	backStates.Lock()
+	defer backStates.Unlock()
+	return backStates.m[handle]
+}
+
+func unregisterBackState(handle uintptr) {
+	backStates.Lock()
+	defer backStates.Unlock()
+	delete(backStates.m, handle)
+}
+
+// cBytes returns a go slice backed by the c memory ptr points to without copying it
+func cBytes(ptr *C.uchar, size C.uint) []byte {
+	if size == 0 {
+		return nil
+	}
+	return (*[1 << 30]byte)(unsafe.Pointer(ptr))[:size:size]
+}
+
+//export goBackIn
+func goBackIn(handle C.uintptr_t, buf *C.uchar, size C.uint) C.uint {
+	s := lookupBackState(uintptr(handle))
+	if s.err != nil {
+		return 0
+	}
+
+	for {
+		n, err := s.src.Read(cBytes(buf, size))
+		if n > 0 {
+			return C.uint(n)
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			return 0
+		}
+	}
+}
+
+//export goBackOut
+func goBackOut(handle C.uintptr_t, buf *C.uchar, size C.uint) C.int {
+	s := lookupBackState(uintptr(handle))
+
+	n, err := s.dst.Write(cBytes(buf, size))
+	s.written += int64(n)
+	if err == nil && n < int(size) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		s.err = err
+		return 1
+	}
+
+	s.adler = C.adler32(s.adler, buf, size)
+	return 0
+}
+
+// InflateBack decompresses the raw deflate stream read from src to dst using inflateBack, which decompresses straight
+// into its 32 KiB window and writes the window to dst whenever it is full, avoiding any further copies of the data.
+// It returns the number of bytes written to dst, the Adler-32 checksum of the written data and the input read from src
+// following the end of the deflate stream, such as a trailer. Errors of src and dst are returned as they are,
+// while input ending before the stream is complete results in io.ErrUnexpectedEOF.
+func InflateBack(dst io.Writer, src io.Reader) (int64, uint32, []byte, error) {
+	b := C.newBackStream(defaultWindowBits, backInputSize)
+	if b == nil {
+		return 0, 0, nil, determineError(errInitialize, C.Z_MEM_ERROR)
+	}
+	defer func() {
+		C.inflateBackEnd(&b.strm)
+		C.freeBackStream(b)
+	}()
+
+	s := &backState{dst: dst, src: src, adler: C.adler32(0, nil, 0)}
+	handle := registerBackState(s)
+	defer unregisterBackState(handle)
+
+	ok := C.runInflateBack(b, C.uintptr_t(handle))
+	if s.err != nil {
+		return s.written, 0, nil, s.err
+	}
+
+	switch ok {
+	case C.Z_STREAM_END:
+		var rest []byte
+		if b.strm.next_in != nil && b.strm.avail_in > 0 {
+			rest = C.GoBytes(unsafe.Pointer(b.strm.next_in), C.int(b.strm.avail_in))
+		}
+		return s.written, uint32(s.adler), rest, nil
+	case C.Z_BUF_ERROR:
+		return s.written, 0, nil, io.ErrUnexpectedEOF
+	}
+	return s.written, 0, nil, determineError(errProcess, ok)
+}
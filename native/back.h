@@ -0,0 +1,24 @@
+#ifndef GO_ZLIB_BACK_H
+#define GO_ZLIB_BACK_H
+
+#include "zlib.h"
+#include "memory.h"
+#include <stdlib.h>
+#include <stdint.h>
+
+// backStream keeps the c memory inflateBack works on: its window, which it decompresses into, and the input buffer
+typedef struct {
+	z_stream strm;
+	unsigned char* window;
+	unsigned char* in;
+	unsigned inSize;
+	uintptr_t handle;
+} backStream;
+
+backStream* newBackStream(int windowBits, unsigned inSize);
+
+void freeBackStream(backStream* b);
+
+int runInflateBack(backStream* b, uintptr_t handle);
+
+#endif
@@ -0,0 +1,63 @@
+package native
+
+/*
+#include "zlib.h"
+#include <stdint.h>
+
+uLong adler32Bytes(uLong adler, int64_t ptr, int64_t size) {
+	return adler32_z(adler, (const Bytef*) ptr, (z_size_t) size);
+}
+
+uLong crc32Bytes(uLong crc, int64_t ptr, int64_t size) {
+	return crc32_z(crc, (const Bytef*) ptr, (z_size_t) size);
+}
+*/
+import "C"
+import (
+	"math"
+	"unsafe"
+)
+
+const (
+	// maxCombineLength is the largest length passed to zlib at once, as z_off_t is only 32 bits wide on some platforms
+	maxCombineLength = math.MaxInt32
+	// adlerBase is the modulus of Adler-32
+	adlerBase = 65521
+)
+
+// Adler32 returns the Adler-32 checksum adler updated with b. The checksum of no data is 1.
+func Adler32(adler uint32, b []byte) uint32 {
+	if len(b) == 0 {
+		return adler
+	}
+	return uint32(C.adler32Bytes(C.uLong(adler), toInt64(int64(uintptr(unsafe.Pointer(&b[0])))), intToInt64(len(b))))
+}
+
+// Adler32Combine returns the Adler-32 checksum of two concatenated chunks of data given the checksums
+// of both chunks and the length of the second one.
+func Adler32Combine(adler1, adler2 uint32, len2 int64) uint32 {
+	// only the length modulo the base matters
+	if len2 > maxCombineLength {
+		len2 %= adlerBase
+	}
+	return uint32(C.adler32_combine(C.uLong(adler1), C.uLong(adler2), C.z_off_t(len2)))
+}
+
+// CRC32 returns the CRC-32 (IEEE) checksum crc updated with b. The checksum of no data is 0.
+func CRC32(crc uint32, b []byte) uint32 {
+	if len(b) == 0 {
+		return crc
+	}
+	return uint32(C.crc32Bytes(C.uLong(crc), toInt64(int64(uintptr(unsafe.Pointer(&b[0])))), intToInt64(len(b))))
+}
+
+// CRC32Combine returns the CRC-32 (IEEE) checksum of two concatenated chunks of data given the checksums
+// of both chunks and the length of the second one.
+func CRC32Combine(crc1, crc2 uint32, len2 int64) uint32 {
+	// combining with 0 only shifts crc1 past the given number of bytes, so long lengths are shifted past in steps
+	for len2 > maxCombineLength {
+		crc1 = uint32(C.crc32_combine(C.uLong(crc1), 0, C.z_off_t(maxCombineLength)))
+		len2 -= maxCombineLength
+	}
+	return uint32(C.crc32_combine(C.uLong(crc1), C.uLong(crc2), C.z_off_t(len2)))
+}
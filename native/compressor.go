@@ -2,24 +2,60 @@ package native
 
 /*
 #include "zlib.h"
+#include "memory.h"
+#include <stdint.h>
 
 // I have no idea why I have to wrap just this function but otherwise cgo won't compile
 int defInit2(z_stream* s, int lvl, int method, int windowBits, int memLevel, int strategy) {
 	return deflateInit2(s, lvl, method, windowBits, memLevel, strategy);
 }
+
+int defSetDictionary(z_stream* s, int64_t dictPtr, int64_t dictSize) {
+	return deflateSetDictionary(s, (Bytef*) dictPtr, (uInt) dictSize);
+}
 */
 import "C"
 import (
 	"fmt"
+	"runtime"
+	"unsafe"
 )
 
 const defaultWindowBits = 15
 const defaultMemLevel = 8
 
+// approximate sizes of the internal states of deflate and inflate apart from their buffers
+const deflateStateSize = 6 * 1024
+const inflateStateSize = 7 * 1024
+
 // Compressor using an underlying C zlib stream to compress (deflate) data
 type Compressor struct {
-	p     processor
-	level int
+	p      processor
+	level  int
+	dict   []byte
+	header *C.gz_header
+}
+
+// CompressorMemory returns the approximate amount of native memory in bytes allocated by a Compressor
+// with the given windowBits and memLevel, following the formula documented in zconf.h.
+// The sign and format offsets of windowBits are ignored.
+func CompressorMemory(windowBits, memLevel int) int {
+	windowBits = windowSize(windowBits)
+	if windowBits == 8 {
+		windowBits = 9 // deflate does not support a window of 256 bytes
+	}
+	return int(C.sizeof_z_stream) + deflateStateSize + 1<<uint(windowBits+2) + 1<<uint(memLevel+9)
+}
+
+// windowSize strips the sign and format offsets from windowBits
+func windowSize(windowBits int) int {
+	if windowBits < 0 {
+		windowBits = -windowBits
+	}
+	for windowBits > defaultWindowBits {
+		windowBits -= GzipWindowOffset
+	}
+	return windowBits
 }
 
 // IsClosed returns whether the StreamCloser has closed the underlying stream
@@ -35,13 +71,58 @@ func NewCompressor(lvl int) (*Compressor, error) {
 // NewCompressorStrategy returns and initializes a new Compressor with given level and strategy
 // with zlib compression stream initialized
 func NewCompressorStrategy(lvl, strat int) (*Compressor, error) {
+	return NewCompressorStrategyDict(lvl, strat, nil)
+}
+
+// NewCompressorStrategyDict returns and initializes a new Compressor with given level, strategy and preset dictionary
+// with zlib compression stream initialized. The dictionary is installed again after every reset of the stream.
+// dict may be nil, in which case no dictionary is used.
+func NewCompressorStrategyDict(lvl, strat int, dict []byte) (*Compressor, error) {
+	return NewCompressorWindow(lvl, strat, defaultWindowBits, dict)
+}
+
+// NewCompressorWindow performs like NewCompressorStrategyDict but you may also specify the windowBits
+// as understood by deflateInit2: 8..15 for the zlib format, -15..-8 for raw deflate without any header or trailer
+// and 24..31 (see GzipWindowOffset) for the gzip format.
+func NewCompressorWindow(lvl, strat, windowBits int, dict []byte) (*Compressor, error) {
+	return NewCompressorMemLevel(lvl, strat, windowBits, defaultMemLevel, dict)
+}
+
+// NewCompressorMemLevel performs like NewCompressorWindow but you may also specify the memLevel (1..9)
+// as understood by deflateInit2, which determines the memory used for the internal compression state.
+func NewCompressorMemLevel(lvl, strat, windowBits, memLevel int, dict []byte) (*Compressor, error) {
 	p := newProcessor()
 
-	if ok := C.defInit2(p.s, C.int(lvl), C.Z_DEFLATED, C.int(defaultWindowBits), C.int(defaultMemLevel), C.int(strat)); ok != C.Z_OK {
+	if ok := C.defInit2(p.s, C.int(lvl), C.Z_DEFLATED, C.int(windowBits), C.int(memLevel), C.int(strat)); ok != C.Z_OK {
+		p.close()
 		return nil, determineError(fmt.Errorf("%s: %s", errInitialize.Error(), "compression level might be invalid"), ok)
 	}
 
-	return &Compressor{p, lvl}, nil
+	c := &Compressor{p, lvl, dict, nil}
+	if ok := c.setDictionary(); ok != C.Z_OK {
+		C.deflateEnd(c.p.s)
+		c.p.close()
+		return nil, determineError(errDictionary, ok)
+	}
+
+	runtime.SetFinalizer(c, (*Compressor).finalize)
+	return c, nil
+}
+
+// setDictionary installs the preset dictionary of the Compressor, if any, on the freshly initialized or reset stream
+func (c *Compressor) setDictionary() C.int {
+	if len(c.dict) == 0 {
+		return C.Z_OK
+	}
+	return C.defSetDictionary(c.p.s, toInt64(int64(uintptr(unsafe.Pointer(&c.dict[0])))), intToInt64(len(c.dict)))
+}
+
+// reset resets the stream and reinstalls the preset dictionary, if any
+func (c *Compressor) reset() C.int {
+	if ok := C.deflateReset(c.p.s); ok != C.Z_OK {
+		return ok
+	}
+	return c.setDictionary()
 }
 
 // Close closes the underlying zlib stream and frees the allocated memory
@@ -65,6 +146,7 @@ func (c *Compressor) Close() ([]byte, error) {
 	ok := C.deflateEnd(c.p.s)
 
 	c.p.close()
+	c.freeHeader()
 
 	if err != nil {
 		return b, err
@@ -76,8 +158,16 @@ func (c *Compressor) Close() ([]byte, error) {
 	return b, err
 }
 
+// Bound returns an upper bound on the compressed size of n bytes compressed in one go,
+// including the header and trailer of the stream
+func (c *Compressor) Bound(n int) int {
+	defer runtime.KeepAlive(c)
+	return int(C.deflateBound(c.p.s, C.uLong(n)))
+}
+
 // Compress compresses the given data and returns it as byte slice
 func (c *Compressor) Compress(in, out []byte) ([]byte, error) {
+	defer runtime.KeepAlive(c)
 	zlibProcess := func() C.int {
 		ok := C.deflate(c.p.s, C.Z_FINISH)
 		if ok != C.Z_STREAM_END {
@@ -87,7 +177,7 @@ func (c *Compressor) Compress(in, out []byte) ([]byte, error) {
 	}
 
 	specificReset := func() C.int {
-		return C.deflateReset(c.p.s)
+		return c.reset()
 	}
 
 	_, b, err := c.p.process(
@@ -101,6 +191,7 @@ func (c *Compressor) Compress(in, out []byte) ([]byte, error) {
 }
 
 func (c *Compressor) CompressStream(in []byte) ([]byte, error) {
+	defer runtime.KeepAlive(c)
 	zlibProcess := func() C.int {
 		return C.deflate(c.p.s, C.Z_NO_FLUSH)
 	}
@@ -130,7 +221,7 @@ func (c *Compressor) compressFinish(in []byte) ([]byte, error) {
 	}
 
 	specificReset := func() C.int {
-		return C.deflateReset(c.p.s)
+		return c.reset()
 	}
 
 	_, b, err := c.p.process(
@@ -143,9 +234,23 @@ func (c *Compressor) compressFinish(in []byte) ([]byte, error) {
 	return b, err
 }
 
+// Flush flushes all pending output using Z_SYNC_FLUSH and returns it
 func (c *Compressor) Flush() ([]byte, error) {
+	defer runtime.KeepAlive(c)
+	return c.FlushMode(int(C.Z_SYNC_FLUSH))
+}
+
+// FlushMode flushes pending output using the given flush mode of deflate
+// (Z_PARTIAL_FLUSH, Z_SYNC_FLUSH, Z_FULL_FLUSH or Z_BLOCK) and returns it.
+// Flushing without any new input since the last flush is not an error.
+func (c *Compressor) FlushMode(mode int) ([]byte, error) {
+	defer runtime.KeepAlive(c)
 	zlibProcess := func() C.int {
-		return C.deflate(c.p.s, C.Z_SYNC_FLUSH)
+		ok := C.deflate(c.p.s, C.int(mode))
+		if ok == C.Z_BUF_ERROR {
+			return C.Z_OK // there was nothing to flush
+		}
+		return ok
 	}
 
 	condition := func() bool {
@@ -162,7 +267,90 @@ func (c *Compressor) Flush() ([]byte, error) {
 	return b, err
 }
 
+// SetParams changes the compression level and strategy of the stream for the data to come.
+// Data compressed before is completed with the previous level and strategy first.
+// The resulting output is returned and must be put in front of any output following the call.
+func (c *Compressor) SetParams(lvl, strat int) ([]byte, error) {
+	defer runtime.KeepAlive(c)
+	out := make([]byte, 0, minWritable)
+
+	if c.p.s.total_in != 0 || c.p.s.total_out != 0 {
+		// deliver all pending data, so that deflateParams can switch on the first try
+		for {
+			out = grow(out, minWritable)
+			_, n, ok := c.p.step(nil, out[len(out):cap(out)], func() C.int {
+				return C.deflate(c.p.s, C.Z_BLOCK)
+			})
+			out = out[:len(out)+n]
+
+			if ok == C.Z_BUF_ERROR || (ok == C.Z_OK && c.p.s.avail_out != 0) {
+				break // nothing (more) to deliver
+			}
+			if ok != C.Z_OK {
+				return out, determineError(errProcess, ok)
+			}
+		}
+	}
+
+	for {
+		out = grow(out, minWritable)
+		_, n, ok := c.p.step(nil, out[len(out):cap(out)], func() C.int {
+			return C.deflateParams(c.p.s, C.int(lvl), C.int(strat))
+		})
+		out = out[:len(out)+n]
+
+		if ok == C.Z_OK {
+			break
+		}
+		if ok != C.Z_BUF_ERROR || n == 0 {
+			return out, determineError(errParams, ok)
+		}
+	}
+
+	c.level = lvl
+	return out, nil
+}
+
+// ResetDict discards the current stream including its pending output and starts a new one
+// with dict as the preset dictionary, which may be nil. The dictionary is kept for the streams to come.
+func (c *Compressor) ResetDict(dict []byte) error {
+	defer runtime.KeepAlive(c)
+	c.dict = dict
+	return determineError(errReset, c.reset())
+}
+
+// Clone returns an independent copy of the Compressor in its current state, including the data not compressed yet.
+// The copy owns its own c memory and must be closed separately.
+func (c *Compressor) Clone() (*Compressor, error) {
+	defer runtime.KeepAlive(c)
+	p := newProcessor()
+	p.hasCompleted, p.readable = c.p.hasCompleted, c.p.readable
+
+	if ok := C.copyDeflate(p.s, c.p.s); ok != C.Z_OK {
+		p.close()
+		return nil, determineError(errClone, ok)
+	}
+
+	clone := &Compressor{p, c.level, c.dict, nil}
+	if ok := clone.copyHeader(c.header); ok != C.Z_OK {
+		C.deflateEnd(clone.p.s)
+		clone.p.close()
+		clone.freeHeader()
+		return nil, determineError(errClone, ok)
+	}
+	runtime.SetFinalizer(clone, (*Compressor).finalize)
+	return clone, nil
+}
+
+// Prime inserts the lowest bits (up to 16) of value into the output stream before any further compressed data.
+// This is meant for raw deflate streams, for example to continue a stream ending in the middle of a byte.
+func (c *Compressor) Prime(bits, value int) error {
+	defer runtime.KeepAlive(c)
+	return determineError(errPrime, C.deflatePrime(c.p.s, C.int(bits), C.int(value)))
+}
+
 func (c *Compressor) Reset() ([]byte, error) {
+	defer runtime.KeepAlive(c)
 	b, err := c.compressFinish([]byte{})
 	if err != nil {
 		return b, err
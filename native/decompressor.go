@@ -1,18 +1,53 @@
 package native
 
 /*
-#include "zlib.h"
+#include "header.h"
+#include "memory.h"
 
 // I have no idea why I have to wrap just this function but otherwise cgo won't compile
-int infInit(z_stream* s) {
-	return inflateInit(s);
+int infInit2(z_stream* s, int windowBits) {
+	return inflateInit2(s, windowBits);
+}
+
+int infSetDictionary(z_stream* s, int64_t dictPtr, int64_t dictSize) {
+	return inflateSetDictionary(s, (Bytef*) dictPtr, (uInt) dictSize);
+}
+
+int infGetDictionary(z_stream* s, int64_t dictPtr, uInt* dictSize) {
+	return inflateGetDictionary(s, (Bytef*) dictPtr, dictSize);
 }
 */
 import "C"
+import (
+	"runtime"
+	"unsafe"
+)
 
 // Decompressor using an underlying c zlib stream to decompress (inflate) data
 type Decompressor struct {
-	p processor
+	p            processor
+	dict         []byte
+	raw          bool
+	header       *C.headerBuffer
+	headerParsed bool
+	parsed       *GzipHeader
+	primed       []primedBits
+}
+
+// primedBits are bits inserted by Prime, which are inserted again if Decompress has to start over
+type primedBits struct {
+	bits, value int
+}
+
+// DecompressorMemory returns the approximate amount of native memory in bytes allocated by a Decompressor
+// with the given windowBits, following the formula documented in zconf.h.
+// The sign and format offsets of windowBits are ignored; 0 is treated like the maximum window.
+func DecompressorMemory(windowBits int) int {
+	windowBits = windowSize(windowBits)
+	if windowBits == 0 {
+		windowBits = defaultWindowBits
+	}
+	return int(C.sizeof_z_stream) + inflateStateSize + 1<<uint(windowBits)
 }
 
 // IsClosed returns whether the StreamCloser has closed the underlying stream
@@ -22,13 +57,36 @@ func (c *Decompressor) IsClosed() bool {
 
 // NewDecompressor returns and initializes a new Decompressor with zlib compression stream initialized
 func NewDecompressor() (*Decompressor, error) {
+	return NewDecompressorDict(nil)
+}
+
+// NewDecompressorDict returns and initializes a new Decompressor with zlib compression stream initialized
+// which supplies dict whenever a stream asks for a preset dictionary. dict may be nil.
+func NewDecompressorDict(dict []byte) (*Decompressor, error) {
+	return NewDecompressorWindow(defaultWindowBits, dict)
+}
+
+// NewDecompressorWindow performs like NewDecompressorDict but you may also specify the windowBits
+// as understood by inflateInit2: 8..15 for the zlib format, -15..-8 for raw deflate without any header or trailer,
+// 24..31 (see GzipWindowOffset) for the gzip format and 40..47 (see AutoWindowOffset) to detect zlib or gzip.
+// As raw deflate streams cannot ask for a dictionary, dict is installed right away and after every reset in that case.
+func NewDecompressorWindow(windowBits int, dict []byte) (*Decompressor, error) {
 	p := newProcessor()
 
-	if ok := C.infInit(p.s); ok != C.Z_OK {
+	if ok := C.infInit2(p.s, C.int(windowBits)); ok != C.Z_OK {
+		p.close()
 		return nil, determineError(errInitialize, ok)
 	}
 
-	return &Decompressor{p}, nil
+	c := &Decompressor{p: p, dict: dict, raw: windowBits < 0}
+	if ok := c.setRawDictionary(); ok != C.Z_OK {
+		C.inflateEnd(c.p.s)
+		c.p.close()
+		return nil, determineError(errDictionary, ok)
+	}
+
+	runtime.SetFinalizer(c, (*Decompressor).finalize)
+	return c, nil
 }
 
 // Close closes the underlying zlib stream and frees the allocated memory
@@ -36,6 +94,7 @@ func (c *Decompressor) Close() error {
 	ok := C.inflateEnd(c.p.s)
 
 	c.p.close()
+	c.freeHeader()
 
 	if ok != C.Z_OK {
 		return determineError(errClose, ok)
@@ -43,11 +102,131 @@ func (c *Decompressor) Close() error {
 	return nil
 }
 
+// Reset resets the underlying zlib stream, keeping the preset dictionary
 func (c *Decompressor) Reset() error {
-	return determineError(errReset, C.inflateReset(c.p.s))
+	defer runtime.KeepAlive(c)
+	return determineError(errReset, c.reset())
+}
+
+// ResetWindow resets the underlying zlib stream like Reset but also changes the windowBits, and with them the format,
+// as understood by inflateInit2 (see NewDecompressorWindow). The preset dictionary is kept.
+func (c *Decompressor) ResetWindow(windowBits int) error {
+	defer runtime.KeepAlive(c)
+	if ok := C.inflateReset2(c.p.s, C.int(windowBits)); ok != C.Z_OK {
+		return determineError(errReset, ok)
+	}
+	c.raw = windowBits < 0
+	c.primed = nil
+
+	if ok := c.installHeader(); ok != C.Z_OK {
+		return determineError(errHeader, ok)
+	}
+	return determineError(errDictionary, c.setRawDictionary())
+}
+
+// Clone returns an independent copy of the Decompressor in its current state, including a partially parsed gzip header.
+// The copy owns its own c memory and must be closed separately.
+func (c *Decompressor) Clone() (*Decompressor, error) {
+	defer runtime.KeepAlive(c)
+	p := newProcessor()
+	p.hasCompleted, p.readable = c.p.hasCompleted, c.p.readable
+
+	if ok := C.copyInflate(p.s, c.p.s); ok != C.Z_OK {
+		p.close()
+		return nil, determineError(errClone, ok)
+	}
+
+	clone := &Decompressor{
+		p:            p,
+		dict:         c.dict,
+		raw:          c.raw,
+		headerParsed: c.headerParsed,
+		parsed:       c.parsed,
+		primed:       append([]primedBits(nil), c.primed...),
+	}
+	if ok := clone.copyHeader(c.header); ok != C.Z_OK {
+		clone.Close()
+		return nil, determineError(errClone, ok)
+	}
+	runtime.SetFinalizer(clone, (*Decompressor).finalize)
+	return clone, nil
+}
+
+// Prime inserts the lowest bits (up to 16) of value into the input bit buffer, as if they preceded the next input.
+// This is meant for raw deflate streams, for example to start decompressing in the middle of a byte.
+func (c *Decompressor) Prime(bits, value int) error {
+	defer runtime.KeepAlive(c)
+	if ok := C.inflatePrime(c.p.s, C.int(bits), C.int(value)); ok != C.Z_OK {
+		return determineError(errPrime, ok)
+	}
+	c.primed = append(c.primed, primedBits{bits, value})
+	return nil
+}
+
+// restart resets the stream like reset, but keeps the bits primed into it
+func (c *Decompressor) restart() C.int {
+	primed := c.primed
+	if ok := c.reset(); ok != C.Z_OK {
+		return ok
+	}
+	for _, p := range primed {
+		if ok := C.inflatePrime(c.p.s, C.int(p.bits), C.int(p.value)); ok != C.Z_OK {
+			return ok
+		}
+	}
+	c.primed = primed
+	return C.Z_OK
+}
+
+// reset resets the stream and installs the preset dictionary right away if this is a raw stream
+func (c *Decompressor) reset() C.int {
+	c.primed = nil
+	if ok := C.inflateReset(c.p.s); ok != C.Z_OK {
+		return ok
+	}
+	if ok := c.installHeader(); ok != C.Z_OK {
+		return ok
+	}
+	return c.setRawDictionary()
+}
+
+func (c *Decompressor) setDictionary() C.int {
+	return C.infSetDictionary(c.p.s, toInt64(int64(uintptr(unsafe.Pointer(&c.dict[0])))), intToInt64(len(c.dict)))
+}
+
+// setRawDictionary installs the preset dictionary, if any, on raw streams which cannot ask for it
+func (c *Decompressor) setRawDictionary() C.int {
+	if !c.raw || len(c.dict) == 0 {
+		return C.Z_OK
+	}
+	return c.setDictionary()
+}
+
+// ResetDict resets the underlying zlib stream and replaces the preset dictionary with dict, which may be nil
+func (c *Decompressor) ResetDict(dict []byte) error {
+	defer runtime.KeepAlive(c)
+	c.dict = dict
+	return c.Reset()
+}
+
+// inflate calls inflate with the given flush mode and supplies the preset dictionary if the stream asks for one.
+// Z_NEED_DICT is returned if there is no dictionary or it does not match the one the stream expects.
+// Parsed gzip headers are captured as well.
+func (c *Decompressor) inflate(flush C.int) C.int {
+	ok := C.inflate(c.p.s, flush)
+	c.captureHeader()
+	if ok != C.Z_NEED_DICT || len(c.dict) == 0 {
+		return ok
+	}
+
+	if c.setDictionary() != C.Z_OK {
+		return C.Z_NEED_DICT
+	}
+	return C.inflate(c.p.s, flush)
 }
 
 func (c *Decompressor) DecompressStream(in, out []byte) (bool, int, []byte, error) {
+	defer runtime.KeepAlive(c)
 	hasCompleted := false
 	condition := func() bool {
 		hasCompleted = c.p.hasCompleted
@@ -55,7 +234,7 @@ func (c *Decompressor) DecompressStream(in, out []byte) (bool, int, []byte, erro
 	}
 
 	zlibProcess := func() C.int {
-		return C.inflate(c.p.s, C.Z_SYNC_FLUSH)
+		return c.inflate(C.Z_SYNC_FLUSH)
 	}
 
 	n, b, err := c.p.process(
@@ -68,10 +247,89 @@ func (c *Decompressor) DecompressStream(in, out []byte) (bool, int, []byte, erro
 	return hasCompleted, n, b, err
 }
 
+// DecompressBlock decompresses in to out with a single call of inflate, which stops at the end of the zlib or gzip header,
+// at the end of every deflate block, or once in is consumed or out is full. It returns the number of bytes consumed
+// and produced and whether the end of the stream has been reached. No progress at all means more input is required.
+// Combined with BlockBoundary, this allows to find the positions at which decompression may be started anew.
+func (c *Decompressor) DecompressBlock(in, out []byte) (int, int, bool, error) {
+	defer runtime.KeepAlive(c)
+	return c.inflateStep(in, out, C.Z_BLOCK)
+}
+
+// Inflate decompresses in to out with a single call of inflate, which stops once in is consumed, out is full
+// or the end of the stream has been reached. Unlike DecompressStream, it never writes past out.
+// It returns the number of bytes consumed and produced and whether the end of the stream has been reached.
+// The stream is not reset at its end.
+func (c *Decompressor) Inflate(in, out []byte) (int, int, bool, error) {
+	defer runtime.KeepAlive(c)
+	return c.inflateStep(in, out, C.Z_SYNC_FLUSH)
+}
+
+func (c *Decompressor) inflateStep(in, out []byte, flush C.int) (int, int, bool, error) {
+	n, m, ok := c.p.step(in, out, func() C.int {
+		return c.inflate(flush)
+	})
+	switch ok {
+	case C.Z_STREAM_END:
+		return n, m, true, nil
+	case C.Z_OK, C.Z_BUF_ERROR:
+		return n, m, false, nil
+	case C.Z_NEED_DICT:
+		return n, m, false, &DictionaryError{ID: uint32(c.p.s.adler)}
+	}
+	return n, m, false, determineError(errProcess, ok)
+}
+
+// BlockBoundary reports whether the last call of DecompressBlock stopped right before the header of a deflate block,
+// which excludes the end of the last block, together with the number of bits (0..7) of the last consumed byte
+// that belong to the next block. Decompression may be started anew at such a boundary given the sliding window
+// (see Dictionary) and the remaining bits, which are the highest bits of that byte (see Prime).
+func (c *Decompressor) BlockBoundary() (bool, int) {
+	defer runtime.KeepAlive(c)
+	dataType := int(c.p.s.data_type)
+	return dataType&128 != 0 && dataType&64 == 0, dataType & 7
+}
+
+// Dictionary returns a copy of the sliding window of the stream, which is the last up to 32 KiB of decompressed data.
+func (c *Decompressor) Dictionary() ([]byte, error) {
+	defer runtime.KeepAlive(c)
+	dict := make([]byte, 1<<defaultWindowBits)
+	var size C.uInt
+
+	ok := C.infGetDictionary(c.p.s, toInt64(int64(uintptr(unsafe.Pointer(&dict[0])))), &size)
+	if ok != C.Z_OK {
+		return nil, determineError(errDictionary, ok)
+	}
+	return dict[:size], nil
+}
+
+// Sync skips input until the next full flush point, from which decompression can continue, for example after
+// corrupted data has been encountered. It returns the number of bytes skipped and whether a full flush point was found.
+// If not, Sync must be called again with the input following the skipped bytes.
+// Once synchronized, the check value of the stream is not verified anymore.
+func (c *Decompressor) Sync(in []byte) (int, bool, error) {
+	defer runtime.KeepAlive(c)
+	if len(in) == 0 {
+		return 0, false, nil
+	}
+
+	n, _, ok := c.p.step(in, nil, func() C.int {
+		return C.inflateSync(c.p.s)
+	})
+	switch ok {
+	case C.Z_OK:
+		return n, true, nil
+	case C.Z_DATA_ERROR: // no full flush point in the input yet
+		return n, false, nil
+	}
+	return n, false, determineError(errSync, ok)
+}
+
 // Decompress decompresses the given data and returns it as byte slice (preferably in one go)
 func (c *Decompressor) Decompress(in, out []byte) (int, []byte, error) {
+	defer runtime.KeepAlive(c)
 	zlibProcess := func() C.int {
-		ok := C.inflate(c.p.s, C.Z_FINISH)
+		ok := c.inflate(C.Z_FINISH)
 		if ok == C.Z_BUF_ERROR {
 			return 10 // retry
 		}
@@ -79,7 +337,7 @@ func (c *Decompressor) Decompress(in, out []byte) (int, []byte, error) {
 	}
 
 	specificReset := func() C.int {
-		return C.inflateReset(c.p.s)
+		return c.reset()
 	}
 
 	if out != nil {
@@ -103,7 +361,7 @@ func (c *Decompressor) Decompress(in, out []byte) (int, []byte, error) {
 		)
 		if err == retry {
 			inc++
-			specificReset()
+			c.restart()
 			continue
 		}
 		return n, b, err
@@ -2,6 +2,7 @@ package native
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
@@ -9,13 +10,44 @@ var (
 	errInitialize = errors.New("native zlib: zlib stream could not be properly initialized")
 	errProcess    = errors.New("native zlib: zlib stream error during in-/deflation")
 	errReset      = errors.New("native zlib: zlib stream could not be properly reset")
+	errDictionary = errors.New("native zlib: preset dictionary could not be installed")
+	errHeader     = errors.New("native zlib: gzip header could not be set")
+	errParams     = errors.New("native zlib: compression level and strategy could not be changed")
+	errPrime      = errors.New("native zlib: bits could not be inserted into the stream")
+	errClone      = errors.New("native zlib: stream could not be copied")
+	errSync       = errors.New("native zlib: stream could not be synchronized")
 
-	errStream  = errors.New("internal state of stream inconsistent: using same stream over mulitiple threads is not advised")
-	errData    = errors.New("data corrupted: data not in a suitable format")
-	errMem     = errors.New("out of memory")
-	errBuf     = errors.New("avail in or avail out zero")
-	errVersion = errors.New("inconsistent zlib version")
-	errUnknown = errors.New("error code returned by native c functions unknown")
+	errStream   = errors.New("internal state of stream inconsistent: using same stream over mulitiple threads is not advised")
+	errNeedDict = errors.New("preset dictionary required")
+	errBuf      = errors.New("avail in or avail out zero")
+	errVersion  = errors.New("inconsistent zlib version")
+	errUnknown  = errors.New("error code returned by native c functions unknown")
 
 	retry = errors.New("zlib: ")
+
+	// ErrData is matched (via errors.Is) by every error caused by corrupted or otherwise invalid input data
+	ErrData = errors.New("data corrupted: data not in a suitable format")
+
+	// ErrMemory is matched (via errors.Is) by every error caused by zlib failing to allocate memory,
+	// either because the system is out of memory or because of the budget set by SetMemoryBudget
+	ErrMemory = errors.New("out of memory or memory budget exceeded")
+
+	// ErrDictionary is matched (via errors.Is) by every DictionaryError
+	ErrDictionary = errors.New("native zlib: invalid dictionary")
 )
+
+// DictionaryError is returned if a stream requires a preset dictionary that was either not provided
+// or does not match the one used for compression
+type DictionaryError struct {
+	// ID is the Adler-32 checksum of the dictionary the stream expects
+	ID uint32
+}
+
+func (e *DictionaryError) Error() string {
+	return fmt.Sprintf("%s: stream requires the dictionary with id %#08x", ErrDictionary.Error(), e.ID)
+}
+
+// Is reports whether target is ErrDictionary
+func (e *DictionaryError) Is(target error) bool {
+	return target == ErrDictionary
+}
@@ -0,0 +1,132 @@
+#include "header.h"
+#include <string.h>
+
+static Bytef* copyBytes(int64_t ptr, int64_t size, int terminate) {
+	Bytef* b = (Bytef*) allocBytes(size + 1);
+	if (b == NULL) {
+		return NULL;
+	}
+	if (size > 0) {
+		memcpy(b, (void*) ptr, size);
+	}
+	if (terminate) {
+		b[size] = 0;
+	}
+	return b;
+}
+
+void freeHeader(gz_header* h) {
+	if (h == NULL) {
+		return;
+	}
+	freeBytes(h->extra);
+	freeBytes(h->name);
+	freeBytes(h->comment);
+	freeBytes(h);
+}
+
+// newHeader copies the given fields into a header allocated in c memory; pointers of 0 leave the field unset
+gz_header* newHeader(int64_t extraPtr, int64_t extraSize, int64_t namePtr, int64_t nameSize,
+		int64_t commentPtr, int64_t commentSize, uLong time, int os) {
+	gz_header* h = (gz_header*) allocZeroed(sizeof(gz_header));
+	if (h == NULL) {
+		return NULL;
+	}
+
+	h->time = time;
+	h->os = os;
+
+	if (extraPtr != 0) {
+		h->extra = copyBytes(extraPtr, extraSize, 0);
+		h->extra_len = h->extra_max = (uInt) extraSize;
+	}
+	if (namePtr != 0) {
+		h->name = copyBytes(namePtr, nameSize, 1);
+	}
+	if (commentPtr != 0) {
+		h->comment = copyBytes(commentPtr, commentSize, 1);
+	}
+
+	if ((extraPtr != 0 && h->extra == NULL) || (namePtr != 0 && h->name == NULL) || (commentPtr != 0 && h->comment == NULL)) {
+		freeHeader(h);
+		return NULL;
+	}
+	return h;
+}
+
+// copyHeader duplicates h including its fields into c memory, as deflate keeps reading them until the header is written
+gz_header* copyHeader(gz_header* h) {
+	if (h == NULL) {
+		return NULL;
+	}
+	return newHeader(
+		(int64_t) h->extra, h->extra_len,
+		(int64_t) h->name, h->name == NULL ? 0 : strlen((char*) h->name),
+		(int64_t) h->comment, h->comment == NULL ? 0 : strlen((char*) h->comment),
+		h->time, h->os);
+}
+
+void freeHeaderBuffer(headerBuffer* b) {
+	if (b == NULL) {
+		return;
+	}
+	freeBytes(b->extra);
+	freeBytes(b->name);
+	freeBytes(b->comment);
+	freeBytes(b);
+}
+
+headerBuffer* newHeaderBuffer(uInt extraMax, uInt nameMax, uInt commMax) {
+	headerBuffer* b = (headerBuffer*) allocZeroed(sizeof(headerBuffer));
+	if (b == NULL) {
+		return NULL;
+	}
+
+	b->extra = (Bytef*) allocBytes(extraMax);
+	b->name = (Bytef*) allocBytes(nameMax);
+	b->comment = (Bytef*) allocBytes(commMax);
+	if (b->extra == NULL || b->name == NULL || b->comment == NULL) {
+		freeHeaderBuffer(b);
+		return NULL;
+	}
+
+	b->head.extra_max = extraMax;
+	b->head.name_max = nameMax;
+	b->head.comm_max = commMax;
+	return b;
+}
+
+int installHeaderBuffer(z_stream* s, headerBuffer* b) {
+	b->head.extra = b->extra;
+	b->head.name = b->name;
+	b->head.comment = b->comment;
+	return inflateGetHeader(s, &b->head);
+}
+
+// copyHeaderBuffer duplicates b including the partially parsed header and installs it on s, which is a copy of the
+// stream b was installed on. Fields inflate has unset stay unset and the progress of parsing is kept.
+headerBuffer* copyHeaderBuffer(z_stream* s, headerBuffer* b) {
+	headerBuffer* c = newHeaderBuffer(b->head.extra_max, b->head.name_max, b->head.comm_max);
+	if (c == NULL) {
+		return NULL;
+	}
+	memcpy(c->extra, b->extra, b->head.extra_max);
+	memcpy(c->name, b->name, b->head.name_max);
+	memcpy(c->comment, b->comment, b->head.comm_max);
+
+	Bytef* extra = b->head.extra == NULL ? NULL : c->extra;
+	Bytef* name = b->head.name == NULL ? NULL : c->name;
+	Bytef* comment = b->head.comment == NULL ? NULL : c->comment;
+	c->head = b->head;
+
+	if (inflateGetHeader(s, &c->head) != Z_OK) {
+		freeHeaderBuffer(c);
+		return NULL;
+	}
+	// inflateGetHeader marks the header as not parsed yet
+	c->head = b->head;
+	c->head.extra = extra;
+	c->head.name = name;
+	c->head.comment = comment;
+	return c;
+}
@@ -0,0 +1,195 @@
+package native
+
+/*
+#include "header.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"runtime"
+	"unsafe"
+)
+
+const (
+	maxHeaderExtra  = 1<<16 - 1
+	maxHeaderString = 1 << 15
+)
+
+const (
+	// GzipWindowOffset is added to the windowBits to select the gzip format instead of the zlib format
+	GzipWindowOffset = 16
+	// AutoWindowOffset is added to the windowBits to have inflate detect whether the zlib or gzip format is used
+	AutoWindowOffset = 32
+)
+
+// GzipHeader represents the fields of a gzip member header (RFC 1952).
+// Name and Comment are raw (ISO 8859-1) bytes without the terminating zero.
+// A nil Extra, Name or Comment is omitted from the header.
+type GzipHeader struct {
+	Name    []byte
+	Comment []byte
+	Extra   []byte
+	ModTime uint32
+	OS      byte
+}
+
+func (h *GzipHeader) toC() *C.gz_header {
+	extraPtr, namePtr, commentPtr := bytesAddress(h.Extra), bytesAddress(h.Name), bytesAddress(h.Comment)
+
+	return C.newHeader(
+		extraPtr, intToInt64(len(h.Extra)),
+		namePtr, intToInt64(len(h.Name)),
+		commentPtr, intToInt64(len(h.Comment)),
+		C.uLong(h.ModTime), C.int(h.OS),
+	)
+}
+
+// bytesAddress returns the address of b, which is also non-zero for empty but non-nil slices, or 0 for nil slices
+func bytesAddress(b []byte) C.int64_t {
+	if b == nil {
+		return 0
+	}
+	return toInt64(int64(uintptr(unsafe.Pointer(startMemAddress(b)))))
+}
+
+// SetHeader sets the gzip header written at the start of the next stream.
+// The Compressor must have been initialized with windowBits in the gzip range (see GzipWindowOffset),
+// and this must be called before any data of the stream has been compressed.
+// The header is kept for the streams to come until it is set again.
+func (c *Compressor) SetHeader(h *GzipHeader) error {
+	defer runtime.KeepAlive(c)
+	head := h.toC()
+	if head == nil {
+		return determineError(errHeader, C.Z_MEM_ERROR)
+	}
+
+	if ok := C.deflateSetHeader(c.p.s, head); ok != C.Z_OK {
+		C.freeHeader(head)
+		return determineError(errHeader, ok)
+	}
+
+	c.freeHeader()
+	c.header = head
+	return nil
+}
+
+// copyHeader installs a copy of h on the stream, which has been copied from the stream h was set on
+func (c *Compressor) copyHeader(h *C.gz_header) C.int {
+	if h == nil {
+		return C.Z_OK
+	}
+	head := C.copyHeader(h)
+	if head == nil {
+		return C.Z_MEM_ERROR
+	}
+	c.header = head
+	return C.deflateSetHeader(c.p.s, head)
+}
+
+func (c *Compressor) freeHeader() {
+	C.freeHeader(c.header)
+	c.header = nil
+}
+
+// EnableHeader makes the Decompressor parse the gzip headers of the streams to come, which are available via Header.
+// The Decompressor must have been initialized with windowBits in the gzip range (see GzipWindowOffset).
+// Name and Comment are truncated to 32 KiB.
+func (c *Decompressor) EnableHeader() error {
+	defer runtime.KeepAlive(c)
+	if c.header == nil {
+		c.header = C.newHeaderBuffer(maxHeaderExtra, maxHeaderString, maxHeaderString)
+		if c.header == nil {
+			return determineError(errHeader, C.Z_MEM_ERROR)
+		}
+	}
+	return determineError(errHeader, c.installHeader())
+}
+
+func (c *Decompressor) installHeader() C.int {
+	if c.header == nil {
+		return C.Z_OK
+	}
+	c.headerParsed = false
+	return C.installHeaderBuffer(c.p.s, c.header)
+}
+
+// captureHeader copies the header into go memory once inflate has completely parsed it
+func (c *Decompressor) captureHeader() {
+	if c.header == nil || c.headerParsed || c.header.head.done != 1 {
+		return
+	}
+	head := &c.header.head
+
+	h := &GzipHeader{
+		ModTime: uint32(head.time),
+		OS:      byte(head.os),
+	}
+	if head.extra != nil {
+		size := head.extra_len
+		if size > head.extra_max {
+			size = head.extra_max
+		}
+		h.Extra = C.GoBytes(unsafe.Pointer(head.extra), C.int(size))
+	}
+	if head.name != nil {
+		h.Name = terminated(C.GoBytes(unsafe.Pointer(head.name), C.int(head.name_max)))
+	}
+	if head.comment != nil {
+		h.Comment = terminated(C.GoBytes(unsafe.Pointer(head.comment), C.int(head.comm_max)))
+	}
+
+	c.parsed = h
+	c.headerParsed = true
+}
+
+func terminated(b []byte) []byte {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return b[:i]
+	}
+	return b
+}
+
+// Header returns the most recently parsed gzip header or nil if none has been parsed yet.
+// It stays available after the stream has been reset until the header of the next stream has been parsed.
+func (c *Decompressor) Header() *GzipHeader {
+	return c.parsed
+}
+
+// DecompressHeader consumes in until the gzip header of the current stream has been parsed completely
+// without decompressing any data. It returns the number of bytes consumed and whether the header is complete.
+// If it is not, DecompressHeader must be called again with the input following the consumed bytes.
+func (c *Decompressor) DecompressHeader(in []byte) (int, bool, error) {
+	defer runtime.KeepAlive(c)
+	if c.headerParsed {
+		return 0, true, nil
+	}
+	if len(in) == 0 {
+		return 0, false, nil
+	}
+
+	n, _, ok := c.p.step(in, make([]byte, 0, 1), func() C.int {
+		return c.inflate(C.Z_BLOCK)
+	})
+	if ok != C.Z_OK && ok != C.Z_BUF_ERROR {
+		return n, false, determineError(errProcess, ok)
+	}
+	return n, c.headerParsed, nil
+}
+
+// copyHeader installs a copy of b on the stream, which has been copied from the stream b was installed on
+func (c *Decompressor) copyHeader(b *C.headerBuffer) C.int {
+	if b == nil {
+		return C.Z_OK
+	}
+	c.header = C.copyHeaderBuffer(c.p.s, b)
+	if c.header == nil {
+		return C.Z_MEM_ERROR
+	}
+	return C.Z_OK
+}
+
+func (c *Decompressor) freeHeader() {
+	C.freeHeaderBuffer(c.header)
+	c.header = nil
+}
@@ -0,0 +1,27 @@
+#include "zlib.h"
+#include "memory.h"
+#include <stdlib.h>
+#include <stdint.h>
+
+// headerBuffer keeps the buffers for parsing headers on inflation, as inflate unsets absent fields in head
+typedef struct {
+	gz_header head;
+	Bytef* extra;
+	Bytef* name;
+	Bytef* comment;
+} headerBuffer;
+
+gz_header* newHeader(int64_t extraPtr, int64_t extraSize, int64_t namePtr, int64_t nameSize,
+		int64_t commentPtr, int64_t commentSize, uLong time, int os);
+
+void freeHeader(gz_header* h);
+
+gz_header* copyHeader(gz_header* h);
+
+headerBuffer* newHeaderBuffer(uInt extraMax, uInt nameMax, uInt commMax);
+
+void freeHeaderBuffer(headerBuffer* b);
+
+int installHeaderBuffer(z_stream* s, headerBuffer* b);
+
+headerBuffer* copyHeaderBuffer(z_stream* s, headerBuffer* b);
@@ -0,0 +1,98 @@
+#include "memory.h"
+#include <string.h>
+
+// every allocation is prefixed by its size, aligned for any type
+#define PREFIX 16
+
+static int64_t total = 0;
+static int64_t budget = 0;
+
+voidpf allocMem(voidpf opaque, uInt items, uInt size) {
+	int64_t n = (int64_t) items * size;
+
+	int64_t cur = __atomic_load_n(&total, __ATOMIC_RELAXED);
+	do {
+		int64_t max = __atomic_load_n(&budget, __ATOMIC_RELAXED);
+		if (max > 0 && cur + n > max) {
+			return Z_NULL;
+		}
+	} while (!__atomic_compare_exchange_n(&total, &cur, cur + n, 1, __ATOMIC_RELAXED, __ATOMIC_RELAXED));
+
+	unsigned char* p = (unsigned char*) malloc(PREFIX + n);
+	if (p == NULL) {
+		__atomic_sub_fetch(&total, n, __ATOMIC_RELAXED);
+		return Z_NULL;
+	}
+	*(int64_t*) p = n;
+	if (opaque != Z_NULL) {
+		((stream*) opaque)->memory += n;
+	}
+	return p + PREFIX;
+}
+
+void freeAllocated(voidpf opaque, voidpf ptr) {
+	unsigned char* p = (unsigned char*) ptr - PREFIX;
+	int64_t n = *(int64_t*) p;
+
+	__atomic_sub_fetch(&total, n, __ATOMIC_RELAXED);
+	if (opaque != Z_NULL) {
+		((stream*) opaque)->memory -= n;
+	}
+	free(p);
+}
+
+// allocBytes allocates n bytes of buffers kept alongside streams, which count towards the total and the budget
+voidpf allocBytes(size_t n) {
+	return allocMem(Z_NULL, 1, (uInt) n);
+}
+
+voidpf allocZeroed(size_t n) {
+	voidpf p = allocBytes(n);
+	if (p != Z_NULL) {
+		memset(p, 0, n);
+	}
+	return p;
+}
+
+void freeBytes(voidpf ptr) {
+	if (ptr != Z_NULL) {
+		freeAllocated(Z_NULL, ptr);
+	}
+}
+
+int64_t streamMemory(z_stream* s) {
+	return ((stream*) s)->memory;
+}
+
+int64_t totalMemory() {
+	return __atomic_load_n(&total, __ATOMIC_RELAXED);
+}
+
+void setMemoryBudget(int64_t max) {
+	__atomic_store_n(&budget, max, __ATOMIC_RELAXED);
+}
+
+// adopt accounts the memory allocated for the copy dest of source to dest, as the copy also takes over
+// the opaque pointer of source, which it has been allocated with
+static void adopt(z_stream* dest, z_stream* source, int64_t before) {
+	stream* d = (stream*) dest;
+	stream* s = (stream*) source;
+
+	dest->opaque = d;
+	d->memory = s->memory - before;
+	s->memory = before;
+}
+
+int copyDeflate(z_stream* dest, z_stream* source) {
+	int64_t before = ((stream*) source)->memory;
+	int ok = deflateCopy(dest, source);
+	adopt(dest, source, before);
+	return ok;
+}
+
+int copyInflate(z_stream* dest, z_stream* source) {
+	int64_t before = ((stream*) source)->memory;
+	int ok = inflateCopy(dest, source);
+	adopt(dest, source, before);
+	return ok;
+}
@@ -0,0 +1,41 @@
+package native
+
+/*
+#include "memory.h"
+*/
+import "C"
+import "runtime"
+
+// Memory returns the number of bytes zlib has currently allocated for the stream, or 0 once it has been closed.
+// A gzip header set on the stream is not included.
+func (c *Compressor) Memory() int {
+	defer runtime.KeepAlive(c)
+	if c.p.isClosed {
+		return 0
+	}
+	return int(C.streamMemory(c.p.s))
+}
+
+// Memory returns the number of bytes zlib has currently allocated for the stream, or 0 once it has been closed.
+// inflate allocates its window once it returns before the end of a stream, so the memory grows with the first data.
+// The buffers for parsing gzip headers are not included.
+func (c *Decompressor) Memory() int {
+	defer runtime.KeepAlive(c)
+	if c.p.isClosed {
+		return 0
+	}
+	return int(C.streamMemory(c.p.s))
+}
+
+// TotalMemory returns the number of bytes zlib has currently allocated for all streams of the process,
+// including gzip header buffers and the streams of InflateBack.
+func TotalMemory() int64 {
+	return int64(C.totalMemory())
+}
+
+// SetMemoryBudget limits the memory zlib may allocate for all streams of the process to budget bytes,
+// 0 meaning no limit. Allocations exceeding it fail, so that creating streams, and inflating with streams
+// that have not allocated their window yet, returns an error matching ErrMemory.
+func SetMemoryBudget(budget int64) {
+	C.setMemoryBudget(C.int64_t(budget))
+}
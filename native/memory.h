@@ -0,0 +1,35 @@
+#ifndef GO_ZLIB_MEMORY_H
+#define GO_ZLIB_MEMORY_H
+
+#include "zlib.h"
+#include <stdlib.h>
+#include <stdint.h>
+
+// stream is a z_stream accounting for the memory zlib allocates for it; strm must stay the first member,
+// so that a stream can be used as a z_stream
+typedef struct {
+	z_stream strm;
+	int64_t memory;
+} stream;
+
+voidpf allocMem(voidpf opaque, uInt items, uInt size);
+
+void freeAllocated(voidpf opaque, voidpf ptr);
+
+voidpf allocBytes(size_t n);
+
+voidpf allocZeroed(size_t n);
+
+void freeBytes(voidpf ptr);
+
+int64_t streamMemory(z_stream* s);
+
+int64_t totalMemory();
+
+void setMemoryBudget(int64_t budget);
+
+int copyDeflate(z_stream* dest, z_stream* source);
+
+int copyInflate(z_stream* dest, z_stream* source);
+
+#endif
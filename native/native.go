@@ -37,15 +37,15 @@ func determineError(parent error, errCode C.int) error {
 	case C.Z_OK:
 		fallthrough
 	case C.Z_STREAM_END:
-		fallthrough
-	case C.Z_NEED_DICT:
 		return nil
+	case C.Z_NEED_DICT:
+		err = errNeedDict
 	case C.Z_STREAM_ERROR:
 		err = errStream
 	case C.Z_DATA_ERROR:
-		err = errData
+		err = ErrData
 	case C.Z_MEM_ERROR:
-		err = errMem
+		err = ErrMemory
 	case C.Z_VERSION_ERROR:
 		err = errVersion
 	case C.Z_BUF_ERROR:
@@ -57,7 +57,7 @@ func determineError(parent error, errCode C.int) error {
 	if parent == nil {
 		return err
 	}
-	return fmt.Errorf("%s: %s", parent.Error(), err.Error())
+	return fmt.Errorf("%s: %w", parent.Error(), err)
 }
 
 func startMemAddress(b []byte) *byte {
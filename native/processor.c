@@ -1,7 +1,14 @@
 #include "processor.h"
 
 z_stream* newStream() {
-	return (z_stream*) calloc(1, sizeof(z_stream));
+	stream* s = (stream*) calloc(1, sizeof(stream));
+	if (s == NULL) {
+		return NULL;
+	}
+	s->strm.zalloc = allocMem;
+	s->strm.zfree = freeAllocated;
+	s->strm.opaque = s;
+	return &s->strm;
 }
 
 void freeMem(z_stream* s) {
@@ -16,7 +16,9 @@ type processor struct {
 }
 
 func newProcessor() processor {
-	return processor{s: C.newStream(), hasCompleted: false, readable: 0, isClosed: false}
+	s := C.newStream()
+	track(s)
+	return processor{s: s, hasCompleted: false, readable: 0, isClosed: false}
 }
 
 func (p *processor) prepare(inPtr uintptr, inSize int, outPtr uintptr, outSize int) {
@@ -34,6 +36,7 @@ func (p *processor) getCompressed() int64 {
 }
 
 func (p *processor) close() {
+	untrack(p.s)
 	C.freeMem(p.s)
 	p.s = nil
 	p.isClosed = true
@@ -67,6 +70,8 @@ func (p *processor) process(in []byte, buf []byte, condition func() bool, zlibPr
 		case C.Z_OK:
 		case 10: // retry with more output space
 			return retry
+		case C.Z_NEED_DICT:
+			return &DictionaryError{ID: uint32(p.s.adler)}
 		default:
 			return determineError(errProcess, ok)
 		}
@@ -99,3 +104,15 @@ func (p *processor) process(in []byte, buf []byte, condition func() bool, zlibPr
 
 	return inIdx, buf, nil
 }
+
+// step runs zlibProcess exactly once on in and out without resetting the stream afterwards.
+// It returns the number of bytes consumed from in, the number of bytes written to out and the result of zlibProcess.
+func (p *processor) step(in, out []byte, zlibProcess func() C.int) (int, int, C.int) {
+	inMem := startMemAddress(in)
+	outMem := startMemAddress(out)
+
+	p.prepare(uintptr(unsafe.Pointer(inMem)), len(in), uintptr(unsafe.Pointer(outMem)), len(out))
+	ok := zlibProcess()
+
+	return int(C.getProcessed(p.s, intToInt64(len(in)))), int(C.getCompressed(p.s, intToInt64(len(out)))), ok
+}
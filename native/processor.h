@@ -1,4 +1,5 @@
 #include "zlib.h"
+#include "memory.h"
 #include <stdlib.h>
 #include <stdint.h>
 
@@ -0,0 +1,106 @@
+package native
+
+/*
+#include "zlib.h"
+*/
+import "C"
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// maxStackDepth is the maximum number of frames recorded for the allocation of a stream
+const maxStackDepth = 32
+
+// LiveStream describes a native stream that has been allocated while tracking was enabled and not freed yet.
+type LiveStream struct {
+	// Stack is the stack trace of the allocation of the stream
+	Stack string
+}
+
+var registry struct {
+	sync.Mutex
+	enabled bool
+	streams map[*C.z_stream]LiveStream
+}
+
+// TrackStreams enables or disables the registry of live streams, which records the allocation stack of every stream.
+// It is meant for debugging leaks, as recording the stack slows down the allocation of streams.
+// Disabling it forgets the streams recorded so far.
+func TrackStreams(enabled bool) {
+	registry.Lock()
+	defer registry.Unlock()
+	if enabled && !registry.enabled {
+		registry.streams = make(map[*C.z_stream]LiveStream)
+	}
+	if !enabled {
+		registry.streams = nil
+	}
+	registry.enabled = enabled
+}
+
+// LiveStreams returns the streams allocated since tracking was enabled that have not been freed yet.
+// Streams freed by a finalizer because they became unreachable without being closed are not included.
+// The streams of InflateTo and the buffers of gzip headers are not recorded in the registry.
+func LiveStreams() []LiveStream {
+	registry.Lock()
+	defer registry.Unlock()
+	streams := make([]LiveStream, 0, len(registry.streams))
+	for _, s := range registry.streams {
+		streams = append(streams, s)
+	}
+	return streams
+}
+
+// track records the allocation of s if tracking is enabled
+func track(s *C.z_stream) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.enabled {
+		registry.streams[s] = LiveStream{Stack: callers()}
+	}
+}
+
+// untrack removes s from the registry
+func untrack(s *C.z_stream) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.streams, s)
+}
+
+// callers formats the stack trace starting at the constructor allocating a stream
+func callers() string {
+	pc := make([]uintptr, maxStackDepth)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(4, pc)])
+
+	b := &strings.Builder{}
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// finalize frees the native memory of a Compressor that has become unreachable without being closed
+func (c *Compressor) finalize() {
+	if c.p.isClosed {
+		return
+	}
+	C.deflateEnd(c.p.s)
+	c.p.close()
+	c.freeHeader()
+}
+
+// finalize frees the native memory of a Decompressor that has become unreachable without being closed
+func (c *Decompressor) finalize() {
+	if c.p.isClosed {
+		return
+	}
+	C.inflateEnd(c.p.s)
+	c.p.close()
+	c.freeHeader()
+}
@@ -0,0 +1,111 @@
+package zlib
+
+import "github.com/4kills/go-zlib/native"
+
+const (
+	minWindowBits = 8
+	maxWindowBits = 15
+
+	minMemLevel     = 1
+	maxMemLevel     = 9
+	defaultMemLevel = 8
+)
+
+// Options configures a Writer created by NewWriterOptions.
+// All options are kept when the Writer is Reset.
+type Options struct {
+	// Level is the compression level. The zero value means DefaultCompression; use StoreOnly for NoCompression.
+	Level int
+	// Strategy is the compression strategy. The zero value is DefaultStrategy.
+	Strategy int
+	// WindowBits is the base two logarithm of the window size (8..15).
+	// Smaller windows need less memory at the cost of compression. The zero value means 15.
+	// A window of 8 bits is changed to 9 bits by zlib.
+	WindowBits int
+	// MemLevel determines how much memory is allocated for the internal compression state (1..9).
+	// Smaller values need less memory at the cost of speed and compression. The zero value means 8.
+	MemLevel int
+	// Dict is the preset dictionary, which may be nil.
+	Dict []byte
+}
+
+// ReaderOptions configures a Reader created by NewReaderOptions.
+// The WindowBits are kept when the Reader is Reset, while the dictionary is replaced by the one passed to Reset.
+type ReaderOptions struct {
+	// WindowBits is the base two logarithm of the window size (8..15). It must be at least the window size
+	// the data was compressed with. Smaller windows need less memory. The zero value means 15.
+	WindowBits int
+	// Dict is the preset dictionary, which may be nil.
+	Dict []byte
+}
+
+func (o Options) validate() (Options, error) {
+	level, err := OptionsLevel(o.Level)
+	if err != nil {
+		return o, err
+	}
+	o.Level = level
+	if o.Strategy < minStrategy || o.Strategy > maxStrategy {
+		return o, errInvalidStrategy
+	}
+
+	if o.WindowBits == 0 {
+		o.WindowBits = defaultWindowBits
+	}
+	if o.WindowBits < minWindowBits || o.WindowBits > maxWindowBits {
+		return o, errInvalidWindowBits
+	}
+
+	if o.MemLevel == 0 {
+		o.MemLevel = defaultMemLevel
+	}
+	if o.MemLevel < minMemLevel || o.MemLevel > maxMemLevel {
+		return o, errInvalidMemLevel
+	}
+	return o, nil
+}
+
+// OptionsLevel returns the compression level that the Level of an option struct like Options stands for,
+// or an error if it is invalid. It allows other option structs to follow the same rules.
+func OptionsLevel(level int) (int, error) {
+	switch level {
+	case 0:
+		return DefaultCompression, nil
+	case StoreOnly:
+		return NoCompression, nil
+	}
+	if level != DefaultCompression && (level < minCompression || level > maxCompression) {
+		return level, errInvalidLevel
+	}
+	return level, nil
+}
+
+func (o ReaderOptions) validate() (ReaderOptions, error) {
+	if o.WindowBits == 0 {
+		o.WindowBits = defaultWindowBits
+	}
+	if o.WindowBits < minWindowBits || o.WindowBits > maxWindowBits {
+		return o, errInvalidWindowBits
+	}
+	return o, nil
+}
+
+// WriterMemory returns the approximate native memory footprint in bytes of a Writer with the given options.
+// It returns an error if the options are invalid.
+func WriterMemory(opts Options) (int, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return 0, err
+	}
+	return native.CompressorMemory(opts.WindowBits, opts.MemLevel), nil
+}
+
+// ReaderMemory returns the approximate native memory footprint in bytes of a Reader with the given options.
+// It returns an error if the options are invalid.
+func ReaderMemory(opts ReaderOptions) (int, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return 0, err
+	}
+	return native.DecompressorMemory(opts.WindowBits), nil
+}
@@ -0,0 +1,135 @@
+package zlib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"testing"
+)
+
+// UNIT TESTS
+
+func TestWriterOptions(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w, err := NewWriterOptions(b, Options{Level: BestCompression, WindowBits: 9, MemLevel: 1, Dict: testDict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := w.Write(longString); err != nil {
+			t.Error(err)
+		}
+		w.Reset(b)
+
+		// the window size is encoded in the CINFO field of the header
+		if info := b.Bytes()[0] >> 4; info != 9-8 {
+			t.Errorf("wrong window size in header: want %d; got %d", 9-8, info)
+		}
+
+		r, err := NewReaderOptions(b, ReaderOptions{WindowBits: 9, Dict: testDict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		act := &bytes.Buffer{}
+		if _, err := io.Copy(act, r); err != nil {
+			t.Error(err)
+		}
+		r.Close()
+		sliceEquals(t, longString, act.Bytes())
+		b.Reset()
+	}
+}
+
+func TestReaderOptions_WindowTooSmall(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(shortString)
+	w.Close()
+
+	r, err := NewReaderOptions(nil, ReaderOptions{WindowBits: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, _, err := r.ReadBuffer(b.Bytes(), nil); err == nil {
+		t.Error("expected an error for a stream with a larger window")
+	}
+}
+
+func TestOptions_Level(t *testing.T) {
+	makeLongString()
+
+	for _, tc := range []struct {
+		level      int
+		compressed bool
+	}{
+		{0, true},
+		{DefaultCompression, true},
+		{BestSpeed, true},
+		{StoreOnly, false},
+	} {
+		w, err := NewWriterOptions(nil, Options{Level: tc.level})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := w.WriteBuffer(longString, nil)
+		w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compressed := len(out) < len(longString); compressed != tc.compressed {
+			t.Errorf("level %d: want compressed %t; got %d bytes for %d", tc.level, tc.compressed, len(out), len(longString))
+		}
+	}
+}
+
+func TestOptions_Invalid(t *testing.T) {
+	invalid := []Options{
+		{Level: 10},
+		{Level: -3},
+		{Strategy: 5},
+		{WindowBits: 7},
+		{WindowBits: 16},
+		{MemLevel: 10},
+		{MemLevel: -1},
+	}
+	for _, opts := range invalid {
+		if _, err := NewWriterOptions(nil, opts); err == nil {
+			t.Errorf("expected options %+v to be invalid", opts)
+		}
+	}
+
+	if _, err := NewReaderOptions(nil, ReaderOptions{WindowBits: 16}); err == nil {
+		t.Error("expected reader options to be invalid")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	def, err := WriterMemory(Options{Level: DefaultCompression})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def < 256*1024 || def > 300*1024 {
+		t.Errorf("unexpected memory for the default configuration: %d", def)
+	}
+
+	small, _ := WriterMemory(Options{WindowBits: 9, MemLevel: 1})
+	if small >= def {
+		t.Errorf("smaller configuration must need less memory: %d >= %d", small, def)
+	}
+
+	rdef, _ := ReaderMemory(ReaderOptions{})
+	rsmall, _ := ReaderMemory(ReaderOptions{WindowBits: 9})
+	if rsmall >= rdef {
+		t.Errorf("smaller configuration must need less memory: %d >= %d", rsmall, rdef)
+	}
+
+	if _, err := WriterMemory(Options{MemLevel: 10}); err == nil {
+		t.Error("expected an error for invalid options")
+	}
+}
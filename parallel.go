@@ -0,0 +1,364 @@
+package zlib
+
+import (
+	"encoding/binary"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+const (
+	// DefaultBlockSize is the default size of the blocks a ParallelWriter compresses concurrently
+	DefaultBlockSize = 128 * 1024
+	// MinBlockSize is the minimum size of the blocks of a ParallelWriter, which is the size of the window
+	MinBlockSize = maxWindowSize
+
+	gzipID1       = 0x1f
+	gzipID2       = 0x8b
+	gzipOSUnknown = 255
+)
+
+// ParallelOptions configures a ParallelWriter created by NewParallelWriter.
+type ParallelOptions struct {
+	// Level is the compression level of every block, following the rules of Options.Level.
+	Level int
+	// Strategy is the compression strategy. The zero value is DefaultStrategy.
+	Strategy int
+	// Concurrency is the number of blocks compressed at the same time. The zero value means runtime.GOMAXPROCS(0).
+	Concurrency int
+	// BlockSize is the size in bytes of the blocks the input is split into. It must be at least MinBlockSize.
+	// The zero value means DefaultBlockSize.
+	BlockSize int
+	// Independent makes every block compress on its own instead of using the last 32 KiB of the data before it
+	// as preset dictionary. This compresses slightly worse, but allows to decompress from every block boundary,
+	// for example by NewRecoveringReader after corrupted data.
+	Independent bool
+	// Gzip selects the gzip format (RFC 1952) with a minimal header instead of the zlib format.
+	Gzip bool
+}
+
+func (o ParallelOptions) validate() (ParallelOptions, error) {
+	level, err := OptionsLevel(o.Level)
+	if err != nil {
+		return o, err
+	}
+	o.Level = level
+	if o.Strategy < minStrategy || o.Strategy > maxStrategy {
+		return o, errInvalidStrategy
+	}
+
+	if o.Concurrency == 0 {
+		o.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	if o.Concurrency < 0 {
+		return o, errInvalidConcurrency
+	}
+
+	if o.BlockSize == 0 {
+		o.BlockSize = DefaultBlockSize
+	}
+	if o.BlockSize < MinBlockSize {
+		return o, errInvalidBlockSize
+	}
+	return o, nil
+}
+
+// ParallelWriter compresses data on multiple goroutines and writes it as a single zlib or gzip stream
+// to an underlying io.Writer, like pigz does. The input is split into blocks, which are compressed
+// as raw deflate by concurrent native streams and joined by sync flushes, while the check value of the
+// stream is combined from the checksums of the blocks.
+// Its output differs from the one of Writer, but is understood by every zlib or gzip reader.
+// Unlike Writer, a ParallelWriter buffers up to Concurrency blocks of compressed data.
+type ParallelWriter struct {
+	w    io.Writer
+	opts ParallelOptions
+
+	block   []byte         // data of the block being filled
+	history []byte         // last up to 32 KiB of the data submitted so far
+	queue   []*parallelJob // blocks submitted but not written yet, in order
+	jobs    chan *parallelJob
+	workers sync.WaitGroup
+
+	check       uint32
+	size        int64
+	wroteHeader bool
+	err         error
+	closed      bool
+}
+
+type parallelJob struct {
+	data  []byte
+	dict  []byte
+	last  bool
+	out   []byte
+	check uint32
+	err   error
+	done  chan struct{}
+}
+
+// NewParallelWriter returns a new ParallelWriter compressing to w configured by the given options, which are validated.
+// It starts Concurrency goroutines, which are stopped by Close.
+func NewParallelWriter(w io.Writer, opts ParallelOptions) (*ParallelWriter, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	zw := &ParallelWriter{
+		w:     w,
+		opts:  opts,
+		block: make([]byte, 0, opts.BlockSize),
+		jobs:  make(chan *parallelJob, opts.Concurrency),
+		check: 1,
+	}
+	if opts.Gzip {
+		zw.check = 0
+	}
+
+	zw.workers.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go zw.work()
+	}
+	return zw, nil
+}
+
+// work compresses submitted blocks with its own native stream until the ParallelWriter is closed
+func (zw *ParallelWriter) work() {
+	defer zw.workers.Done()
+
+	var c *native.Compressor
+	defer func() {
+		if c != nil {
+			c.Close()
+		}
+	}()
+
+	for job := range zw.jobs {
+		if c == nil {
+			c, job.err = native.NewCompressorWindow(zw.opts.Level, zw.opts.Strategy, rawWindowBits, nil)
+		}
+		if job.err == nil {
+			job.compress(c, zw.opts.Gzip)
+		}
+		close(job.done)
+	}
+}
+
+// compress compresses the block as raw deflate ending with a sync flush, or with the final block if it is the last one
+func (j *parallelJob) compress(c *native.Compressor, gzip bool) {
+	if gzip {
+		j.check = native.CRC32(0, j.data)
+	} else {
+		j.check = native.Adler32(1, j.data)
+	}
+
+	if j.err = c.ResetDict(j.dict); j.err != nil {
+		return
+	}
+	if j.last {
+		j.out, j.err = c.Compress(j.data, make([]byte, 0, c.Bound(len(j.data))))
+		return
+	}
+
+	if j.out, j.err = c.CompressStream(j.data); j.err != nil {
+		return
+	}
+	flushed, err := c.Flush()
+	j.out, j.err = append(j.out, flushed...), err
+}
+
+// Write splits p into blocks and hands full blocks to the compressing goroutines.
+// Compressed data is written to the underlying writer once enough blocks are in progress.
+// It returns the number of *uncompressed* bytes accepted.
+func (zw *ParallelWriter) Write(p []byte) (int, error) {
+	if zw.closed {
+		return 0, errIsClosed
+	}
+	if zw.err != nil {
+		return 0, zw.err
+	}
+
+	n := 0
+	for n < len(p) {
+		copied := copy(zw.block[len(zw.block):cap(zw.block)], p[n:])
+		zw.block = zw.block[:len(zw.block)+copied]
+		n += copied
+
+		if len(zw.block) < cap(zw.block) {
+			break
+		}
+		if err := zw.submit(false); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// submit hands the current block to the compressing goroutines and writes the oldest blocks
+// to the underlying writer if too many are in progress
+func (zw *ParallelWriter) submit(last bool) error {
+	job := &parallelJob{data: zw.block, last: last, done: make(chan struct{})}
+	if !zw.opts.Independent {
+		job.dict = zw.history
+	}
+	zw.history = appendHistory(zw.history, zw.block)
+	zw.block = make([]byte, 0, zw.opts.BlockSize)
+
+	zw.queue = append(zw.queue, job)
+	zw.jobs <- job
+
+	if len(zw.queue) <= zw.opts.Concurrency {
+		return nil
+	}
+	return zw.writeQueued(len(zw.queue) - zw.opts.Concurrency)
+}
+
+// appendHistory returns a new slice of the last up to 32 KiB of history followed by data
+func appendHistory(history, data []byte) []byte {
+	if len(data) >= maxWindowSize {
+		return append([]byte(nil), data[len(data)-maxWindowSize:]...)
+	}
+	keep := maxWindowSize - len(data)
+	if keep > len(history) {
+		keep = len(history)
+	}
+
+	h := make([]byte, 0, keep+len(data))
+	h = append(h, history[len(history)-keep:]...)
+	return append(h, data...)
+}
+
+// writeQueued waits for the n oldest blocks to be compressed and writes them to the underlying writer
+func (zw *ParallelWriter) writeQueued(n int) error {
+	for i := 0; i < n; i++ {
+		job := zw.queue[0]
+		zw.queue = zw.queue[1:]
+		<-job.done
+
+		if zw.err != nil {
+			continue
+		}
+		if job.err != nil {
+			zw.err = job.err
+			continue
+		}
+		if !zw.wroteHeader {
+			zw.wroteHeader = true
+			zw.err = zw.write(zw.header())
+		}
+		if zw.err == nil {
+			zw.err = zw.write(job.out)
+		}
+
+		if zw.opts.Gzip {
+			zw.check = native.CRC32Combine(zw.check, job.check, int64(len(job.data)))
+		} else {
+			zw.check = native.Adler32Combine(zw.check, job.check, int64(len(job.data)))
+		}
+		zw.size += int64(len(job.data))
+	}
+	return zw.err
+}
+
+func (zw *ParallelWriter) write(b []byte) error {
+	if zw.w == nil {
+		return nil
+	}
+	_, err := zw.w.Write(b)
+	return err
+}
+
+// header returns the zlib header or the minimal gzip header
+func (zw *ParallelWriter) header() []byte {
+	level := zw.opts.Level
+	if level == DefaultCompression {
+		level = 6
+	}
+
+	if zw.opts.Gzip {
+		var flags byte
+		switch level {
+		case BestCompression:
+			flags = 2
+		case BestSpeed:
+			flags = 4
+		}
+		return []byte{gzipID1, gzipID2, zlibDeflated, 0, 0, 0, 0, 0, flags, gzipOSUnknown}
+	}
+
+	// the compression level flags as set by deflate
+	var flags uint16
+	switch {
+	case zw.opts.Strategy >= HuffmanOnly || level < 2:
+		flags = 0
+	case level < 6:
+		flags = 1
+	case level == 6:
+		flags = 2
+	default:
+		flags = 3
+	}
+	header := uint16(zlibDeflated|(maxWindowBits-8)<<4)<<8 | flags<<6
+	header += 31 - header%31
+
+	b := make([]byte, zlibHeaderSize)
+	binary.BigEndian.PutUint16(b, header)
+	return b
+}
+
+// trailer returns the zlib or gzip trailer
+func (zw *ParallelWriter) trailer() []byte {
+	if zw.opts.Gzip {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint32(b, zw.check)
+		binary.LittleEndian.PutUint32(b[4:], uint32(zw.size))
+		return b
+	}
+
+	b := make([]byte, zlibTrailerSize)
+	binary.BigEndian.PutUint32(b, zw.check)
+	return b
+}
+
+// Flush compresses the buffered data and writes all compressed data to the underlying writer,
+// so that a reader is able to decompress everything written so far.
+// The compression of the blocks to come cannot start before the flush is complete.
+func (zw *ParallelWriter) Flush() error {
+	if zw.closed {
+		return errIsClosed
+	}
+	if zw.err != nil {
+		return zw.err
+	}
+
+	if len(zw.block) != 0 {
+		if err := zw.submit(false); err != nil {
+			return err
+		}
+	}
+	return zw.writeQueued(len(zw.queue))
+}
+
+// Close compresses the buffered data, writes all compressed data and the trailer to the underlying writer
+// and stops the compressing goroutines. It does not close the underlying writer.
+// You should not forget to call this after being done with the writer.
+func (zw *ParallelWriter) Close() error {
+	if zw.closed {
+		return errIsClosed
+	}
+
+	if zw.err == nil {
+		zw.submit(true)
+	}
+	zw.writeQueued(len(zw.queue))
+	if zw.err == nil {
+		zw.err = zw.write(zw.trailer())
+	}
+
+	zw.closed = true
+	close(zw.jobs)
+	zw.workers.Wait()
+	return zw.err
+}
@@ -0,0 +1,190 @@
+package zlib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+// makeParallelTestData returns 1 MiB of pseudo-random lines, whose words recur across the blocks of a ParallelWriter
+func makeParallelTestData() []byte {
+	words := [][]byte{[]byte("parallel "), []byte("block "), []byte("writer "), []byte("history "), []byte("checksum "), []byte("\n")}
+	rnd := rand.New(rand.NewSource(1))
+
+	b := &bytes.Buffer{}
+	for b.Len() < 1<<20 {
+		b.Write(words[rnd.Intn(len(words))])
+		b.WriteByte(byte('a' + rnd.Intn(26)))
+	}
+	return b.Bytes()
+}
+
+func decompressParallel(t *testing.T, gz bool, compressed []byte) []byte {
+	t.Helper()
+
+	var r io.ReadCloser
+	var err error
+	if gz {
+		r, err = gzip.NewReader(bytes.NewReader(compressed))
+	} else {
+		r, err = zlib.NewReader(bytes.NewReader(compressed))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return act
+}
+
+func TestParallelWriter(t *testing.T) {
+	data := makeParallelTestData()
+
+	for _, opts := range []ParallelOptions{
+		{Level: DefaultCompression},
+		{Level: BestSpeed, Concurrency: 4, BlockSize: MinBlockSize},
+		{Level: BestCompression, Concurrency: 1, BlockSize: MinBlockSize + 1},
+		{Level: DefaultCompression, Concurrency: 3, Independent: true},
+		{Level: StoreOnly, Strategy: HuffmanOnly, Concurrency: 2},
+		{},
+		{Level: DefaultCompression, Concurrency: 4, BlockSize: MinBlockSize, Gzip: true},
+		{Level: BestCompression, Independent: true, Gzip: true},
+	} {
+		for _, size := range []int{0, 1, MinBlockSize, len(data)} {
+			b := &bytes.Buffer{}
+			w, err := NewParallelWriter(b, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// uneven writes to cross the block boundaries
+			for in := data[:size]; len(in) != 0; {
+				n := min64(int64(len(in)), 10007)
+				if _, err := w.Write(in[:n]); err != nil {
+					t.Fatal(err)
+				}
+				in = in[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if act := decompressParallel(t, opts.Gzip, b.Bytes()); !bytes.Equal(act, data[:size]) {
+				t.Errorf("%+v, size %d: decompressed data does not match the input", opts, size)
+			}
+		}
+	}
+}
+
+func TestParallelWriter_Dictionary(t *testing.T) {
+	data := makeParallelTestData()
+
+	compress := func(independent bool) int {
+		b := &bytes.Buffer{}
+		w, err := NewParallelWriter(b, ParallelOptions{Level: DefaultCompression, BlockSize: MinBlockSize, Independent: independent})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return b.Len()
+	}
+
+	if dependent, independent := compress(false), compress(true); dependent >= independent {
+		t.Errorf("priming with the previous block should compress better: %d >= %d", dependent, independent)
+	}
+}
+
+func TestParallelWriter_Flush(t *testing.T) {
+	data := makeParallelTestData()
+
+	b := &bytes.Buffer{}
+	w, err := NewParallelWriter(b, ParallelOptions{Level: DefaultCompression, Concurrency: 2, BlockSize: MinBlockSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data[:100000]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := make([]byte, 100000)
+	if _, err := io.ReadFull(r, act); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(act, data[:100000]) {
+		t.Error("flushed data does not match the input")
+	}
+
+	if _, err := w.Write(data[100000:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if act := decompressParallel(t, false, b.Bytes()); !bytes.Equal(act, data) {
+		t.Error("decompressed data does not match the input")
+	}
+
+	if _, err := w.Write(data); err != errIsClosed {
+		t.Errorf("want %v; got %v", errIsClosed, err)
+	}
+	if err := w.Close(); err != errIsClosed {
+		t.Errorf("want %v; got %v", errIsClosed, err)
+	}
+}
+
+func TestParallelWriter_Level(t *testing.T) {
+	data := makeParallelTestData()
+
+	compress := func(level int) int {
+		b := &bytes.Buffer{}
+		w, err := NewParallelWriter(b, ParallelOptions{Level: level})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return b.Len()
+	}
+
+	if def, stored := compress(0), compress(StoreOnly); def >= len(data) || stored < len(data) {
+		t.Errorf("want the zero level to compress and StoreOnly to store %d bytes; got %d and %d bytes", len(data), def, stored)
+	}
+}
+
+func TestParallelWriter_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		opts ParallelOptions
+		err  error
+	}{
+		{ParallelOptions{Level: 10}, errInvalidLevel},
+		{ParallelOptions{Level: -3}, errInvalidLevel},
+		{ParallelOptions{Strategy: -1}, errInvalidStrategy},
+		{ParallelOptions{Concurrency: -1}, errInvalidConcurrency},
+		{ParallelOptions{BlockSize: MinBlockSize - 1}, errInvalidBlockSize},
+	} {
+		if _, err := NewParallelWriter(ioutil.Discard, tc.opts); err != tc.err {
+			t.Errorf("%+v: want %v; got %v", tc.opts, tc.err, err)
+		}
+	}
+}
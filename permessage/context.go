@@ -0,0 +1,230 @@
+package permessage
+
+import (
+	"bytes"
+
+	"github.com/4kills/go-zlib"
+	"github.com/4kills/go-zlib/native"
+)
+
+// DefaultMaxMessageSize is the default limit of decompressed messages
+const DefaultMaxMessageSize = 32 << 20
+
+// minGrowth is the least number of bytes the output of a message grows by
+const minGrowth = 8192
+
+// tail is the empty stored block ending each sync flush, which is removed from every message
+var tail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// Compressor compresses the messages sent in one direction of a connection.
+// It is not safe for concurrent use.
+type Compressor struct {
+	compressor *native.Compressor
+	takeover   bool
+}
+
+// NewCompressor returns a new Compressor compressing with the given level (see the zlib package) and window bits
+// (9..15, as zlib cannot compress raw deflate with a window of 256 bytes). With contextTakeover, messages may
+// refer to the messages compressed before, otherwise every message is compressed on its own.
+func NewCompressor(level, windowBits int, contextTakeover bool) (*Compressor, error) {
+	if level != zlib.DefaultCompression && (level < zlib.NoCompression || level > zlib.BestCompression) {
+		return nil, errInvalidLevel
+	}
+	if windowBits < minCompressorWindowBits || windowBits > MaxWindowBits {
+		return nil, errInvalidWindowBits
+	}
+
+	c, err := native.NewCompressorWindow(level, zlib.DefaultStrategy, -windowBits, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Compressor{compressor: c, takeover: contextTakeover}, nil
+}
+
+// Compress compresses message and returns the payload of the frame to send, which has the RSV1 bit set.
+func (c *Compressor) Compress(message []byte) ([]byte, error) {
+	if err := checkClosed(c.compressor); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if len(message) != 0 {
+		var err error
+		if out, err = c.compressor.CompressStream(message); err != nil {
+			return nil, err
+		}
+	}
+	flushed, err := c.compressor.Flush()
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, flushed...)
+
+	// deflate does not repeat a flush without new input, but an empty stored block stands for an empty message
+	switch {
+	case len(out) == 0:
+		out = []byte{0x00}
+	case bytes.HasSuffix(out, tail):
+		out = out[:len(out)-len(tail)]
+	default:
+		return nil, errMissingTail
+	}
+
+	if !c.takeover {
+		if err := c.compressor.ResetDict(nil); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Close closes the Compressor by closing and freeing the underlying zlib stream.
+// You should not forget to call this after being done with the Compressor.
+func (c *Compressor) Close() error {
+	if err := checkClosed(c.compressor); err != nil {
+		return err
+	}
+	_, err := c.compressor.Close()
+	return err
+}
+
+// Decompressor decompresses the messages received in one direction of a connection.
+// It is not safe for concurrent use.
+type Decompressor struct {
+	decompressor *native.Decompressor
+	takeover     bool
+	in           []byte
+	max          int64
+}
+
+// NewDecompressor returns a new Decompressor for messages compressed with a window of up to
+// windowBits (8..15). With contextTakeover, messages may refer to the messages received before.
+// Messages are limited to DefaultMaxMessageSize, see SetMaxMessageSize.
+func NewDecompressor(windowBits int, contextTakeover bool) (*Decompressor, error) {
+	if windowBits < MinWindowBits || windowBits > MaxWindowBits {
+		return nil, errInvalidWindowBits
+	}
+
+	c, err := native.NewDecompressorWindow(-windowBits, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Decompressor{decompressor: c, takeover: contextTakeover, max: DefaultMaxMessageSize}, nil
+}
+
+// Decompress decompresses the payload of a frame with the RSV1 bit set and returns the message.
+func (d *Decompressor) Decompress(payload []byte) ([]byte, error) {
+	if err := checkClosed(d.decompressor); err != nil {
+		return nil, err
+	}
+
+	d.in = append(append(d.in[:0], payload...), tail...)
+	end, out, err := d.inflate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !d.takeover:
+		err = d.decompressor.ResetDict(nil)
+	case end:
+		// the sender has ended the deflate stream by a final block, but may still refer to its window
+		var window []byte
+		if window, err = d.decompressor.Dictionary(); err == nil {
+			err = d.decompressor.ResetDict(window)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetMaxMessageSize limits the size of decompressed messages to max bytes, so that a small frame cannot inflate
+// to a huge message. Decompress fails with ErrMessageTooLarge once a message exceeds it, without allocating
+// more than the limit, and the connection has to be failed (with close code 1009).
+// The zero value means DefaultMaxMessageSize, a negative value means no limit.
+func (d *Decompressor) SetMaxMessageSize(max int64) {
+	if max == 0 {
+		max = DefaultMaxMessageSize
+	}
+	d.max = max
+}
+
+// inflate decompresses the buffered input, growing the output up to one byte past the limit to detect exceeding it
+func (d *Decompressor) inflate() (bool, []byte, error) {
+	in := d.in
+	out := make([]byte, 0, d.capacity(2*len(in)))
+	for {
+		n, m, end, err := d.decompressor.Inflate(in, out[len(out):cap(out)])
+		in = in[n:]
+		out = out[:len(out)+m]
+		switch {
+		case err != nil:
+			return false, nil, err
+		case d.max >= 0 && int64(len(out)) > d.max:
+			return false, nil, ErrMessageTooLarge
+		case end || len(out) < cap(out):
+			return end, out, nil
+		}
+
+		grown := make([]byte, len(out), d.capacity(2*cap(out)+minGrowth))
+		out = grown[:copy(grown, out)]
+	}
+}
+
+// capacity limits the capacity n of the output to one byte past the maximum message size
+func (d *Decompressor) capacity(n int) int {
+	if d.max >= 0 && int64(n)-1 > d.max {
+		return int(d.max + 1)
+	}
+	return n
+}
+
+// Close closes the Decompressor by closing and freeing the underlying zlib stream.
+// You should not forget to call this after being done with the Decompressor.
+func (d *Decompressor) Close() error {
+	if err := checkClosed(d.decompressor); err != nil {
+		return err
+	}
+	d.in = nil
+	return d.decompressor.Close()
+}
+
+// Context holds the Compressor and Decompressor of one endpoint of a connection.
+// It is not safe for concurrent use, but its Compressor and Decompressor may be used concurrently.
+type Context struct {
+	*Compressor
+	*Decompressor
+}
+
+// NewContext returns the Context of the server or the client of a connection as negotiated by params,
+// which compresses with the given level (see the zlib package).
+func NewContext(params Params, server bool, level int) (*Context, error) {
+	sendBits, sendTakeover := params.clientWindowBits(), !params.ClientNoContextTakeover
+	recvBits, recvTakeover := params.serverWindowBits(), !params.ServerNoContextTakeover
+	if server {
+		sendBits, recvBits = recvBits, sendBits
+		sendTakeover, recvTakeover = recvTakeover, sendTakeover
+	}
+
+	c, err := NewCompressor(level, sendBits, sendTakeover)
+	if err != nil {
+		return nil, err
+	}
+	d, err := NewDecompressor(recvBits, recvTakeover)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &Context{Compressor: c, Decompressor: d}, nil
+}
+
+// Close closes both the Compressor and the Decompressor.
+func (c *Context) Close() error {
+	err := c.Compressor.Close()
+	if derr := c.Decompressor.Close(); err == nil {
+		err = derr
+	}
+	return err
+}
@@ -0,0 +1,256 @@
+package permessage
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/4kills/go-zlib"
+)
+
+func testMessages() [][]byte {
+	var messages [][]byte
+	for i := 0; i < 20; i++ {
+		messages = append(messages, []byte(fmt.Sprintf(`{"id":%d,"type":"update","payload":"%s"}`, i, bytes.Repeat([]byte("data"), i*10))))
+	}
+	// an empty message and one larger than every window
+	messages = append(messages, []byte{}, bytes.Repeat([]byte("0123456789abcdef"), 1<<12))
+	return append(messages, messages[3])
+}
+
+// flateFrames compresses the messages with compress/flate like a permessage-deflate peer would
+func flateFrames(t *testing.T, messages [][]byte, takeover bool) [][]byte {
+	t.Helper()
+
+	b := &bytes.Buffer{}
+	w, _ := flate.NewWriter(b, flate.DefaultCompression)
+	var frames [][]byte
+	for _, m := range messages {
+		if !takeover {
+			w.Reset(b)
+		}
+		w.Write(m)
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasSuffix(b.Bytes(), tail) {
+			t.Fatal("flate did not end the flush with an empty stored block")
+		}
+		frames = append(frames, append([]byte(nil), b.Bytes()[:b.Len()-len(tail)]...))
+		b.Reset()
+	}
+	return frames
+}
+
+func TestCompressor(t *testing.T) {
+	messages := testMessages()
+
+	for _, takeover := range []bool{true, false} {
+		for _, bits := range []int{9, 12, MaxWindowBits} {
+			c, err := NewCompressor(zlib.DefaultCompression, bits, takeover)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a single flate reader decompresses the whole stream in case of context takeover
+			pr, pw := io.Pipe()
+			fr := flate.NewReader(pr)
+			for i, m := range messages {
+				payload, err := c.Compress(m)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(m) == 0 && !bytes.Equal(payload, []byte{0}) {
+					t.Errorf("an empty message should compress to a single 0x00 byte: %x", payload)
+				}
+
+				if !takeover {
+					fr = flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(tail)))
+				} else {
+					go func() {
+						pw.Write(payload)
+						pw.Write(tail)
+					}()
+				}
+
+				act := make([]byte, len(m))
+				if _, err := io.ReadFull(fr, act); err != nil {
+					t.Fatalf("takeover %v, bits %d, message %d: %v", takeover, bits, i, err)
+				}
+				if !bytes.Equal(act, m) {
+					t.Errorf("takeover %v, bits %d, message %d: decompressed message does not match", takeover, bits, i)
+				}
+			}
+			pw.Close()
+			c.Close()
+		}
+	}
+}
+
+func TestCompressor_ContextTakeover(t *testing.T) {
+	m := testMessages()[10]
+	size := func(takeover bool) int {
+		c, err := NewCompressor(zlib.DefaultCompression, MaxWindowBits, takeover)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.Compress(m)
+		payload, err := c.Compress(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(payload)
+	}
+
+	if with, without := size(true), size(false); with >= without {
+		t.Errorf("a repeated message should compress better with context takeover: %d >= %d", with, without)
+	}
+}
+
+func TestDecompressor(t *testing.T) {
+	messages := testMessages()
+
+	for _, takeover := range []bool{true, false} {
+		d, err := NewDecompressor(MaxWindowBits, takeover)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, frame := range flateFrames(t, messages, takeover) {
+			act, err := d.Decompress(frame)
+			if err != nil {
+				t.Fatalf("takeover %v, message %d: %v", takeover, i, err)
+			}
+			if !bytes.Equal(act, messages[i]) {
+				t.Errorf("takeover %v, message %d: decompressed message does not match", takeover, i)
+			}
+		}
+		d.Close()
+	}
+}
+
+func TestDecompressor_MaxMessageSize(t *testing.T) {
+	const size = 10 << 20
+	message := make([]byte, size)
+	frame := flateFrames(t, [][]byte{message}, false)[0]
+
+	for _, tc := range []struct {
+		max int64
+		err error
+	}{
+		{0, nil},
+		{1 << 20, ErrMessageTooLarge},
+		{size - 1, ErrMessageTooLarge},
+		{size, nil},
+		{-1, nil},
+	} {
+		d, err := NewDecompressor(MaxWindowBits, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.SetMaxMessageSize(tc.max)
+
+		act, err := d.Decompress(frame)
+		if err != tc.err {
+			t.Errorf("%d: want %v; got %v", tc.max, tc.err, err)
+		}
+		if tc.err == nil && !bytes.Equal(act, message) {
+			t.Errorf("%d: decompressed message does not match", tc.max)
+		}
+		d.Close()
+	}
+}
+
+func TestDecompressor_FinalBlock(t *testing.T) {
+	messages := testMessages()[:3]
+
+	d, err := NewDecompressor(MaxWindowBits, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// every message ends the deflate stream, the second one refers to the first one
+	b := &bytes.Buffer{}
+	for i, m := range messages {
+		w, _ := flate.NewWriterDict(b, flate.DefaultCompression, messages[0])
+		if i == 0 {
+			w, _ = flate.NewWriter(b, flate.DefaultCompression)
+		}
+		w.Write(m)
+		w.Close()
+
+		act, err := d.Decompress(b.Bytes())
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if !bytes.Equal(act, m) {
+			t.Errorf("message %d: decompressed message does not match", i)
+		}
+		b.Reset()
+	}
+}
+
+func TestContext(t *testing.T) {
+	offer := ParseOffers("permessage-deflate; client_max_window_bits; server_max_window_bits=10")[0]
+	resp, err := Negotiate(offer, Params{ClientMaxWindowBits: 11, ClientNoContextTakeover: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Accept(offer, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewContext(resp, true, zlib.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client, err := NewContext(resp, false, zlib.BestSpeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i, m := range testMessages() {
+		for _, dir := range [][2]*Context{{server, client}, {client, server}} {
+			payload, err := dir[0].Compress(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			act, err := dir[1].Decompress(payload)
+			if err != nil {
+				t.Fatalf("message %d: %v", i, err)
+			}
+			if !bytes.Equal(act, m) {
+				t.Errorf("message %d: decompressed message does not match", i)
+			}
+		}
+	}
+}
+
+func TestContext_Invalid(t *testing.T) {
+	if _, err := NewCompressor(10, MaxWindowBits, true); err != errInvalidLevel {
+		t.Errorf("want %v; got %v", errInvalidLevel, err)
+	}
+	if _, err := NewCompressor(zlib.DefaultCompression, MinWindowBits, true); err != errInvalidWindowBits {
+		t.Errorf("want %v; got %v", errInvalidWindowBits, err)
+	}
+	if _, err := NewDecompressor(MaxWindowBits+1, true); err != errInvalidWindowBits {
+		t.Errorf("want %v; got %v", errInvalidWindowBits, err)
+	}
+
+	d, err := NewDecompressor(MinWindowBits, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Decompress([]byte{0xff, 0xff, 0xff}); err == nil {
+		t.Error("expected an error for corrupted data")
+	}
+	d.Close()
+	if _, err := d.Decompress([]byte{0}); err != errIsClosed {
+		t.Errorf("want %v; got %v", errIsClosed, err)
+	}
+}
@@ -0,0 +1,20 @@
+package permessage
+
+import (
+	"errors"
+)
+
+var (
+	errIsClosed          = errors.New("permessage: context is already closed: you may not use this anymore")
+	errInvalidLevel      = errors.New("permessage: invalid compression level provided")
+	errInvalidWindowBits = errors.New("permessage: invalid window bits provided")
+	errInvalidParams     = errors.New("permessage: invalid extension parameters")
+	errMissingTail       = errors.New("permessage: flushed data does not end with an empty stored block")
+
+	// ErrMessageTooLarge is returned by Decompress if a message exceeds the maximum message size
+	ErrMessageTooLarge = errors.New("permessage: decompressed message exceeds the size limit")
+
+	// ErrDeclined is returned by Negotiate and Accept if the parameters cannot be agreed on,
+	// in which case the connection has to do without permessage-deflate (server) or has to be failed (client).
+	ErrDeclined = errors.New("permessage: extension parameters declined")
+)
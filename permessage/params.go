@@ -0,0 +1,233 @@
+// Package permessage implements the permessage-deflate extension of WebSocket (RFC 7692) on top of native zlib:
+// the negotiation of its parameters and the compression and decompression contexts of a connection.
+package permessage
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// ExtensionName is the name of the extension in the Sec-WebSocket-Extensions header
+	ExtensionName = "permessage-deflate"
+
+	// MinWindowBits is the smallest window size (as base two logarithm) the extension allows
+	MinWindowBits = 8
+	// MaxWindowBits is the largest window size (as base two logarithm) the extension allows, which is the default
+	MaxWindowBits = 15
+	// minCompressorWindowBits is the smallest window size zlib compresses raw deflate with
+	minCompressorWindowBits = 9
+
+	serverNoContextTakeover = "server_no_context_takeover"
+	clientNoContextTakeover = "client_no_context_takeover"
+	serverMaxWindowBits     = "server_max_window_bits"
+	clientMaxWindowBits     = "client_max_window_bits"
+)
+
+// Params are the parameters of the permessage-deflate extension, as offered by a client or accepted by a server.
+// A window bits value of 0 means the parameter is absent. In an offer, client_max_window_bits without a value
+// is represented by MaxWindowBits, as both allow the server to choose any value.
+type Params struct {
+	// ServerNoContextTakeover forbids the server to use the messages it sent before for compression.
+	ServerNoContextTakeover bool
+	// ClientNoContextTakeover forbids the client to use the messages it sent before for compression.
+	ClientNoContextTakeover bool
+	// ServerMaxWindowBits limits the window the server compresses with.
+	ServerMaxWindowBits int
+	// ClientMaxWindowBits limits the window the client compresses with. In an offer, it signals that
+	// the client supports the parameter and limits the value the server may respond with.
+	ClientMaxWindowBits int
+}
+
+// String formats the parameters as extension element of the Sec-WebSocket-Extensions header.
+func (p Params) String() string {
+	b := &strings.Builder{}
+	b.WriteString(ExtensionName)
+	if p.ServerNoContextTakeover {
+		b.WriteString("; " + serverNoContextTakeover)
+	}
+	if p.ClientNoContextTakeover {
+		b.WriteString("; " + clientNoContextTakeover)
+	}
+	if p.ServerMaxWindowBits != 0 {
+		b.WriteString("; " + serverMaxWindowBits + "=" + strconv.Itoa(p.ServerMaxWindowBits))
+	}
+	if p.ClientMaxWindowBits != 0 {
+		b.WriteString("; " + clientMaxWindowBits + "=" + strconv.Itoa(p.ClientMaxWindowBits))
+	}
+	return b.String()
+}
+
+// serverWindowBits returns the window size the server compresses with
+func (p Params) serverWindowBits() int {
+	if p.ServerMaxWindowBits == 0 {
+		return MaxWindowBits
+	}
+	return p.ServerMaxWindowBits
+}
+
+// clientWindowBits returns the window size the client compresses with
+func (p Params) clientWindowBits() int {
+	if p.ClientMaxWindowBits == 0 {
+		return MaxWindowBits
+	}
+	return p.ClientMaxWindowBits
+}
+
+// ParseOffers returns the permessage-deflate offers in the value of a Sec-WebSocket-Extensions header in order
+// of preference. Other extensions and offers with unknown, duplicate or invalid parameters are skipped,
+// as a server has to decline them.
+func ParseOffers(header string) []Params {
+	var offers []Params
+	for _, element := range strings.Split(header, ",") {
+		p, ok, err := parseElement(element, true)
+		if ok && err == nil {
+			offers = append(offers, p)
+		}
+	}
+	return offers
+}
+
+// ParseResponse returns the permessage-deflate parameters in the value of the Sec-WebSocket-Extensions header
+// of a server's response and whether the server has accepted the extension at all.
+// An error is returned for unknown, duplicate or invalid parameters, upon which a client has to fail the connection.
+func ParseResponse(header string) (Params, bool, error) {
+	for _, element := range strings.Split(header, ",") {
+		if p, ok, err := parseElement(element, false); ok {
+			return p, true, err
+		}
+	}
+	return Params{}, false, nil
+}
+
+// parseElement parses an extension element and reports whether it is permessage-deflate
+func parseElement(element string, offer bool) (Params, bool, error) {
+	parts := strings.Split(element, ";")
+	if strings.TrimSpace(parts[0]) != ExtensionName {
+		return Params{}, false, nil
+	}
+
+	var p Params
+	seen := make(map[string]bool)
+	for _, part := range parts[1:] {
+		name, value := strings.TrimSpace(part), ""
+		hasValue := false
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name, value, hasValue = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:]), true
+		}
+		if seen[name] {
+			return p, true, errInvalidParams
+		}
+		seen[name] = true
+
+		switch name {
+		case serverNoContextTakeover, clientNoContextTakeover:
+			if hasValue {
+				return p, true, errInvalidParams
+			}
+			if name == serverNoContextTakeover {
+				p.ServerNoContextTakeover = true
+			} else {
+				p.ClientNoContextTakeover = true
+			}
+		case serverMaxWindowBits:
+			bits, err := parseWindowBits(value)
+			if err != nil {
+				return p, true, err
+			}
+			p.ServerMaxWindowBits = bits
+		case clientMaxWindowBits:
+			if !hasValue && offer {
+				p.ClientMaxWindowBits = MaxWindowBits
+				continue
+			}
+			bits, err := parseWindowBits(value)
+			if err != nil {
+				return p, true, err
+			}
+			p.ClientMaxWindowBits = bits
+		default:
+			return p, true, errInvalidParams
+		}
+	}
+	return p, true, nil
+}
+
+// parseWindowBits parses a window bits value, which may be quoted, but must not have leading zeros
+func parseWindowBits(value string) (int, error) {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	if len(value) == 0 || value[0] == '0' || strings.Trim(value, "0123456789") != "" {
+		return 0, errInvalidParams
+	}
+	bits, err := strconv.Atoi(value)
+	if err != nil || bits < MinWindowBits || bits > MaxWindowBits {
+		return 0, errInvalidParams
+	}
+	return bits, nil
+}
+
+// Negotiate returns the parameters a server responds with to the offer of a client, given the preferences of
+// the server in config: the window sizes of both directions may be limited and context takeover may be disabled.
+// Whatever the client has asked for is granted in addition. As zlib cannot compress raw deflate with a window
+// of 256 bytes, ErrDeclined is returned if the server would have to compress with MinWindowBits,
+// or if the client offers to compress with it. A client window of MinWindowBits configured by the server
+// is raised to the smallest window the client can compress with.
+func Negotiate(offer, config Params) (Params, error) {
+	resp := Params{
+		ServerNoContextTakeover: offer.ServerNoContextTakeover || config.ServerNoContextTakeover,
+		ClientNoContextTakeover: offer.ClientNoContextTakeover || config.ClientNoContextTakeover,
+	}
+
+	server := minBits(config.serverWindowBits(), offer.serverWindowBits())
+	if server < minCompressorWindowBits {
+		return Params{}, ErrDeclined
+	}
+	// an offered server_max_window_bits has to be confirmed
+	if server != MaxWindowBits || offer.ServerMaxWindowBits != 0 {
+		resp.ServerMaxWindowBits = server
+	}
+
+	// the client window can only be limited if the client supports the parameter
+	if offer.ClientMaxWindowBits != 0 {
+		// a client cannot compress with MinWindowBits either, so it gets the smallest window it can use
+		if offer.ClientMaxWindowBits < minCompressorWindowBits {
+			return Params{}, ErrDeclined
+		}
+		client := minBits(config.clientWindowBits(), offer.ClientMaxWindowBits)
+		if client < minCompressorWindowBits {
+			client = minCompressorWindowBits
+		}
+		if client != MaxWindowBits {
+			resp.ClientMaxWindowBits = client
+		}
+	}
+	return resp, nil
+}
+
+// Accept checks the parameters a server has responded with against the offer of the client
+// and returns ErrDeclined if the client has to fail the connection, which includes a client window
+// of MinWindowBits, as zlib cannot compress raw deflate with a window of 256 bytes.
+func Accept(offer, resp Params) error {
+	if offer.ServerNoContextTakeover && !resp.ServerNoContextTakeover {
+		return ErrDeclined
+	}
+	if offer.ServerMaxWindowBits != 0 && (resp.ServerMaxWindowBits == 0 || resp.ServerMaxWindowBits > offer.ServerMaxWindowBits) {
+		return ErrDeclined
+	}
+	if resp.ClientMaxWindowBits != 0 && (offer.ClientMaxWindowBits == 0 || resp.ClientMaxWindowBits > offer.ClientMaxWindowBits) {
+		return ErrDeclined
+	}
+	if resp.clientWindowBits() < minCompressorWindowBits {
+		return ErrDeclined
+	}
+	return nil
+}
+
+func minBits(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
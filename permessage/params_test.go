@@ -0,0 +1,98 @@
+package permessage
+
+import (
+	"testing"
+)
+
+func TestParseOffers(t *testing.T) {
+	header := `x-webkit-deflate-frame, permessage-deflate; client_max_window_bits; server_max_window_bits="10",` +
+		` permessage-deflate; server_max_window_bits=08, permessage-deflate; unknown, permessage-deflate;` +
+		` server_no_context_takeover; client_no_context_takeover; client_max_window_bits=9`
+
+	offers := ParseOffers(header)
+	exp := []Params{
+		{ClientMaxWindowBits: MaxWindowBits, ServerMaxWindowBits: 10},
+		{ServerNoContextTakeover: true, ClientNoContextTakeover: true, ClientMaxWindowBits: 9},
+	}
+	if len(offers) != len(exp) {
+		t.Fatalf("want %d offers; got %+v", len(exp), offers)
+	}
+	for i := range exp {
+		if offers[i] != exp[i] {
+			t.Errorf("offer %d: want %+v; got %+v", i, exp[i], offers[i])
+		}
+		// the formatted parameters parse to the same
+		if again := ParseOffers(offers[i].String()); len(again) != 1 || again[0] != offers[i] {
+			t.Errorf("offer %d: %q does not parse back", i, offers[i].String())
+		}
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		params Params
+		ok     bool
+		err    error
+	}{
+		{"", Params{}, false, nil},
+		{"x-other", Params{}, false, nil},
+		{"permessage-deflate", Params{}, true, nil},
+		{"permessage-deflate; server_max_window_bits=12; client_no_context_takeover", Params{ServerMaxWindowBits: 12, ClientNoContextTakeover: true}, true, nil},
+		{"permessage-deflate; client_max_window_bits", Params{}, true, errInvalidParams},
+		{"permessage-deflate; server_max_window_bits=16", Params{}, true, errInvalidParams},
+		{"permessage-deflate; server_no_context_takeover; server_no_context_takeover", Params{}, true, errInvalidParams},
+		{"permessage-deflate; server_no_context_takeover=1", Params{}, true, errInvalidParams},
+	} {
+		params, ok, err := ParseResponse(tc.header)
+		if ok != tc.ok || err != tc.err || (err == nil && params != tc.params) {
+			t.Errorf("%q: want %+v, %v, %v; got %+v, %v, %v", tc.header, tc.params, tc.ok, tc.err, params, ok, err)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		offer, config, resp Params
+		err                 error
+	}{
+		{Params{}, Params{}, Params{}, nil},
+		{Params{ServerNoContextTakeover: true}, Params{ClientNoContextTakeover: true}, Params{ServerNoContextTakeover: true, ClientNoContextTakeover: true}, nil},
+		{Params{ServerMaxWindowBits: 15}, Params{}, Params{ServerMaxWindowBits: 15}, nil},
+		{Params{ServerMaxWindowBits: 12}, Params{ServerMaxWindowBits: 10}, Params{ServerMaxWindowBits: 10}, nil},
+		{Params{}, Params{ServerMaxWindowBits: 10}, Params{ServerMaxWindowBits: 10}, nil},
+		{Params{}, Params{ClientMaxWindowBits: 10}, Params{}, nil},
+		{Params{ClientMaxWindowBits: 15}, Params{ClientMaxWindowBits: 10}, Params{ClientMaxWindowBits: 10}, nil},
+		{Params{ClientMaxWindowBits: 9}, Params{ClientMaxWindowBits: 10}, Params{ClientMaxWindowBits: 9}, nil},
+		{Params{ClientMaxWindowBits: 15}, Params{ClientMaxWindowBits: 8}, Params{ClientMaxWindowBits: 9}, nil},
+		{Params{ServerMaxWindowBits: 8}, Params{}, Params{}, ErrDeclined},
+		{Params{ClientMaxWindowBits: 8}, Params{}, Params{}, ErrDeclined},
+	} {
+		resp, err := Negotiate(tc.offer, tc.config)
+		if resp != tc.resp || err != tc.err {
+			t.Errorf("%+v, %+v: want %+v, %v; got %+v, %v", tc.offer, tc.config, tc.resp, tc.err, resp, err)
+		}
+		if err == nil {
+			if err := Accept(tc.offer, resp); err != nil {
+				t.Errorf("%+v: negotiated %+v is not accepted: %v", tc.offer, resp, err)
+			}
+		}
+	}
+}
+
+func TestAccept_Declined(t *testing.T) {
+	for _, tc := range []struct {
+		offer, resp Params
+	}{
+		{Params{ServerNoContextTakeover: true}, Params{}},
+		{Params{ServerMaxWindowBits: 10}, Params{}},
+		{Params{ServerMaxWindowBits: 10}, Params{ServerMaxWindowBits: 11}},
+		{Params{}, Params{ClientMaxWindowBits: 10}},
+		{Params{ClientMaxWindowBits: 10}, Params{ClientMaxWindowBits: 11}},
+		{Params{ClientMaxWindowBits: 15}, Params{ClientMaxWindowBits: 8}},
+	} {
+		if err := Accept(tc.offer, tc.resp); err != ErrDeclined {
+			t.Errorf("%+v, %+v: want %v; got %v", tc.offer, tc.resp, ErrDeclined, err)
+		}
+	}
+}
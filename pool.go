@@ -0,0 +1,343 @@
+package zlib
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// PoolOptions configures a Pool created by NewPool.
+type PoolOptions struct {
+	// MaxStreams is the maximum number of live native streams of the Pool, idle or in use, over all configurations.
+	// The zero value means no limit.
+	MaxStreams int
+	// Wait makes GetWriter and GetReader block until a stream is put back once MaxStreams is reached,
+	// instead of failing with ErrPoolExhausted.
+	Wait bool
+	// IdleTimeout is the time after which idle streams are closed. It is applied periodically, so streams
+	// may stay idle for up to twice as long. The zero value means they are kept until the Pool is closed.
+	IdleTimeout time.Duration
+}
+
+func (o PoolOptions) validate() error {
+	if o.MaxStreams < 0 || o.IdleTimeout < 0 {
+		return errInvalidPoolOptions
+	}
+	return nil
+}
+
+// poolKey is the configuration a native stream has been allocated with; the dictionary is set on every Get
+type poolKey struct {
+	reader     bool
+	level      int
+	strategy   int
+	windowBits int
+	memLevel   int
+}
+
+// pooled is a Writer or Reader of the Pool
+type pooled struct {
+	key   poolKey
+	w     *Writer
+	r     *Reader
+	since time.Time
+}
+
+func (e *pooled) close() error {
+	if e.w != nil {
+		return e.w.Close()
+	}
+	return e.r.Close()
+}
+
+// Pool keeps Writers and Readers for reuse, so that their native streams are not allocated anew for every use.
+// Unlike sync.Pool, it closes the streams it drops, caps the number of live streams and frees idle ones
+// after a timeout. Writers and Readers are pooled by their configuration apart from the dictionary.
+// A Pool is safe for concurrent use and has to be closed after use.
+type Pool struct {
+	opts   PoolOptions
+	mu     sync.Mutex
+	cond   *sync.Cond
+	idle   map[poolKey][]*pooled
+	inUse  map[interface{}]*pooled
+	live   int
+	closed bool
+	done   chan struct{}
+}
+
+// NewPool returns a new Pool configured by the given options, which are validated.
+func NewPool(opts PoolOptions) (*Pool, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	p := &Pool{
+		opts:  opts,
+		idle:  make(map[poolKey][]*pooled),
+		inUse: make(map[interface{}]*pooled),
+		done:  make(chan struct{}),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	if opts.IdleTimeout > 0 {
+		go p.janitor()
+	}
+	return p, nil
+}
+
+// GetWriter returns a Writer configured by opts, which are validated, writing to w.
+// The Writer is taken from the Pool if there is an idle one of the same configuration and newly allocated otherwise.
+// It has to be returned with PutWriter instead of being closed.
+// w may be nil if you only plan on using WriteBuffer.
+func (p *Pool) GetWriter(w io.Writer, opts Options) (*Writer, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	key := poolKey{level: opts.Level, strategy: opts.Strategy, windowBits: opts.WindowBits, memLevel: opts.MemLevel}
+
+	e, err := p.acquire(key)
+	if err != nil {
+		return nil, err
+	}
+	if e.w != nil {
+		if err := e.w.compressor.ResetDict(opts.Dict); err != nil {
+			p.drop(e)
+			return nil, err
+		}
+		e.w.w = w
+	} else if e.w, err = NewWriterOptions(w, opts); err != nil {
+		p.release(e)
+		return nil, err
+	}
+
+	p.lend(e, e.w)
+	return e.w, nil
+}
+
+// PutWriter finishes the stream of zw like Close, writing the remaining compressed data to its underlying writer,
+// and returns zw to the Pool instead of freeing its native stream. zw must not be used afterwards.
+// If zw has been closed or its level or strategy changed by SetParams, its stream is dropped instead.
+func (p *Pool) PutWriter(zw *Writer) error {
+	e, err := p.reclaim(zw)
+	if err != nil {
+		return err
+	}
+	if checkClosed(zw.compressor) != nil {
+		p.release(e)
+		return nil
+	}
+
+	b, err := zw.compressor.Reset()
+	if err != nil {
+		p.drop(e)
+		return err
+	}
+	if zw.w != nil {
+		_, err = zw.w.Write(b)
+	}
+	zw.w = nil
+
+	if zw.level != e.key.level || zw.strategy != e.key.strategy {
+		p.drop(e)
+		return err
+	}
+	p.giveBack(e)
+	return err
+}
+
+// GetReader returns a Reader configured by opts, which are validated, reading from r.
+// The Reader is taken from the Pool if there is an idle one of the same configuration and newly allocated otherwise.
+// It has to be returned with PutReader instead of being closed.
+// r may be nil if you only plan on using ReadBuffer.
+func (p *Pool) GetReader(r io.Reader, opts ReaderOptions) (*Reader, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := p.acquire(poolKey{reader: true, windowBits: opts.WindowBits})
+	if err != nil {
+		return nil, err
+	}
+	if e.r != nil {
+		if err := e.r.Reset(r, opts.Dict); err != nil {
+			p.drop(e)
+			return nil, err
+		}
+	} else if e.r, err = NewReaderOptions(r, opts); err != nil {
+		p.release(e)
+		return nil, err
+	}
+
+	p.lend(e, e.r)
+	return e.r, nil
+}
+
+// PutReader returns zr to the Pool, discarding the rest of its stream. zr must not be used afterwards.
+// Multistream mode and limits are switched off for the next user. If zr has been closed, its stream is dropped instead.
+func (p *Pool) PutReader(zr *Reader) error {
+	e, err := p.reclaim(zr)
+	if err != nil {
+		return err
+	}
+	if checkClosed(zr.decompressor) != nil {
+		p.release(e)
+		return nil
+	}
+
+	if err := zr.Reset(nil, nil); err != nil {
+		p.drop(e)
+		return err
+	}
+	zr.multistream = false
+	zr.limits = Limits{}
+	p.giveBack(e)
+	return nil
+}
+
+// Close closes the idle streams of the Pool and stops freeing them after the IdleTimeout.
+// Streams still in use are closed when they are put back. Blocked calls of GetWriter and GetReader fail.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errPoolClosed
+	}
+	p.closed = true
+	close(p.done)
+	p.cond.Broadcast()
+
+	var err error
+	for key, idle := range p.idle {
+		for _, e := range idle {
+			if cerr := e.close(); err == nil {
+				err = cerr
+			}
+			p.live--
+		}
+		delete(p.idle, key)
+	}
+	return err
+}
+
+// acquire returns an idle stream of the configuration, or an empty entry to allocate a new one for
+func (p *Pool) acquire(key poolKey) (*pooled, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if p.closed {
+			return nil, errPoolClosed
+		}
+		if idle := p.idle[key]; len(idle) != 0 {
+			// the most recently used stream is the most likely to be in the cache
+			e := idle[len(idle)-1]
+			p.idle[key] = idle[:len(idle)-1]
+			return e, nil
+		}
+		if p.opts.MaxStreams == 0 || p.live < p.opts.MaxStreams || p.evict() {
+			p.live++
+			return &pooled{key: key}, nil
+		}
+		if !p.opts.Wait {
+			return nil, ErrPoolExhausted
+		}
+		p.cond.Wait()
+	}
+}
+
+// evict closes the idle stream unused for the longest time to make room for one of another configuration
+func (p *Pool) evict() bool {
+	var oldest *pooled
+	for _, idle := range p.idle {
+		if len(idle) != 0 && (oldest == nil || idle[0].since.Before(oldest.since)) {
+			oldest = idle[0]
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	p.idle[oldest.key] = p.idle[oldest.key][1:]
+	oldest.close()
+	p.live--
+	return true
+}
+
+func (p *Pool) lend(e *pooled, v interface{}) {
+	p.mu.Lock()
+	p.inUse[v] = e
+	p.mu.Unlock()
+}
+
+// reclaim takes back a Writer or Reader lent by the Pool
+func (p *Pool) reclaim(v interface{}) (*pooled, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.inUse[v]
+	if !ok {
+		return nil, errNotPooled
+	}
+	delete(p.inUse, v)
+	return e, nil
+}
+
+// giveBack makes a stream idle again, or closes it if the Pool has been closed
+func (p *Pool) giveBack(e *pooled) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		e.close()
+		p.live--
+		return
+	}
+	e.since = time.Now()
+	p.idle[e.key] = append(p.idle[e.key], e)
+	p.cond.Signal()
+}
+
+// drop closes a stream that cannot be reused
+func (p *Pool) drop(e *pooled) {
+	e.close()
+	p.release(e)
+}
+
+// release gives up the slot of a stream that is closed or has never been allocated
+func (p *Pool) release(*pooled) {
+	p.mu.Lock()
+	p.live--
+	p.cond.Signal()
+	p.mu.Unlock()
+}
+
+// janitor closes the streams that have been idle for longer than the IdleTimeout until the Pool is closed
+func (p *Pool) janitor() {
+	ticker := time.NewTicker(p.opts.IdleTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.closeIdle(now.Add(-p.opts.IdleTimeout))
+		}
+	}
+}
+
+// closeIdle closes the idle streams that have not been used since the given time
+func (p *Pool) closeIdle(since time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, idle := range p.idle {
+		n := 0
+		for n < len(idle) && idle[n].since.Before(since) {
+			idle[n].close()
+			p.live--
+			n++
+		}
+		if n == len(idle) {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = idle[n:]
+		}
+	}
+	p.cond.Broadcast()
+}
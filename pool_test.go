@@ -0,0 +1,217 @@
+package zlib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func newTestPool(t *testing.T, opts PoolOptions) *Pool {
+	t.Helper()
+
+	p, err := NewPool(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// liveStreams returns the number of live streams of p
+func (p *Pool) liveStreams() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.live
+}
+
+func TestPool_Writer(t *testing.T) {
+	p := newTestPool(t, PoolOptions{})
+	defer p.Close()
+
+	var first *Writer
+	for i, dict := range [][]byte{nil, shortString, nil} {
+		b := &bytes.Buffer{}
+		w, err := p.GetWriter(b, Options{Level: BestSpeed, Dict: dict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = w
+		} else if w != first {
+			t.Errorf("%d: the idle writer has not been reused", i)
+		}
+		w.Write(shortString)
+		if err := p.PutWriter(w); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := zlib.NewReaderDict(b, dict)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if act, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(act, shortString) {
+			t.Errorf("%d: decompressed data does not match: %v", i, err)
+		}
+	}
+
+	// another configuration gets a stream of its own
+	w, err := p.GetWriter(nil, Options{Level: BestCompression})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w == first || p.liveStreams() != 2 {
+		t.Error("a writer of another configuration has been reused")
+	}
+	p.PutWriter(w)
+}
+
+func TestPool_Reader(t *testing.T) {
+	p := newTestPool(t, PoolOptions{})
+	defer p.Close()
+
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(shortString)
+	w.Close()
+
+	var first *Reader
+	for i := 0; i < 3; i++ {
+		r, err := p.GetReader(bytes.NewReader(b.Bytes()), ReaderOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = r
+			r.Multistream(true)
+			r.SetLimits(Limits{MaxOutput: 1})
+		} else if r != first || r.multistream || r.limits.enabled() {
+			t.Errorf("%d: the idle reader has not been reused or reset", i)
+		}
+
+		act, err := ioutil.ReadAll(r)
+		if i != 0 && (err != nil || !bytes.Equal(act, shortString)) {
+			t.Errorf("%d: decompressed data does not match: %v", i, err)
+		}
+		if err := p.PutReader(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPool_MaxStreams(t *testing.T) {
+	p := newTestPool(t, PoolOptions{MaxStreams: 2})
+	defer p.Close()
+
+	w, _ := p.GetWriter(nil, Options{})
+	r, _ := p.GetReader(nil, ReaderOptions{})
+	if _, err := p.GetReader(nil, ReaderOptions{}); err != ErrPoolExhausted {
+		t.Errorf("want %v; got %v", ErrPoolExhausted, err)
+	}
+
+	// the idle writer is closed to make room for the reader
+	p.PutWriter(w)
+	r2, err := p.GetReader(nil, ReaderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.compressor.IsClosed() || p.liveStreams() != 2 {
+		t.Error("the idle writer has not been evicted")
+	}
+	p.PutReader(r)
+	p.PutReader(r2)
+}
+
+func TestPool_Wait(t *testing.T) {
+	p := newTestPool(t, PoolOptions{MaxStreams: 1, Wait: true})
+
+	w, _ := p.GetWriter(nil, Options{})
+	got := make(chan *Writer)
+	go func() {
+		w, err := p.GetWriter(nil, Options{})
+		if err != nil {
+			t.Error(err)
+		}
+		got <- w
+	}()
+
+	select {
+	case <-got:
+		t.Fatal("the stream has been handed out twice")
+	case <-time.After(10 * time.Millisecond):
+	}
+	p.PutWriter(w)
+	if w2 := <-got; w2 != w {
+		t.Error("the returned writer has not been handed to the waiting caller")
+	}
+
+	// closing the pool wakes waiting callers
+	errs := make(chan error)
+	go func() {
+		_, err := p.GetReader(nil, ReaderOptions{})
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+	if err := <-errs; err != errPoolClosed {
+		t.Errorf("want %v; got %v", errPoolClosed, err)
+	}
+	if p.PutWriter(w); !w.compressor.IsClosed() {
+		t.Error("the writer put back after Close has not been closed")
+	}
+}
+
+func TestPool_IdleTimeout(t *testing.T) {
+	p := newTestPool(t, PoolOptions{IdleTimeout: 10 * time.Millisecond})
+	defer p.Close()
+
+	r, _ := p.GetReader(nil, ReaderOptions{})
+	p.PutReader(r)
+	for i := 0; p.liveStreams() != 0; i++ {
+		if i == 100 {
+			t.Fatal("the idle stream has not been closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !r.decompressor.IsClosed() {
+		t.Error("the idle reader has not been closed")
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	p := newTestPool(t, PoolOptions{})
+
+	w, _ := p.GetWriter(nil, Options{})
+	p.PutWriter(w)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !w.compressor.IsClosed() || p.liveStreams() != 0 {
+		t.Error("the idle writer has not been closed")
+	}
+	if _, err := p.GetWriter(nil, Options{}); err != errPoolClosed {
+		t.Errorf("want %v; got %v", errPoolClosed, err)
+	}
+	if err := p.Close(); err != errPoolClosed {
+		t.Errorf("want %v; got %v", errPoolClosed, err)
+	}
+}
+
+func TestPool_Invalid(t *testing.T) {
+	for _, opts := range []PoolOptions{{MaxStreams: -1}, {IdleTimeout: -1}} {
+		if _, err := NewPool(opts); err != errInvalidPoolOptions {
+			t.Errorf("%+v: want %v; got %v", opts, errInvalidPoolOptions, err)
+		}
+	}
+
+	p := newTestPool(t, PoolOptions{})
+	defer p.Close()
+	if _, err := p.GetWriter(nil, Options{Level: 42}); err != errInvalidLevel {
+		t.Errorf("want %v; got %v", errInvalidLevel, err)
+	}
+	w := NewWriter(nil)
+	defer w.Close()
+	if err := p.PutWriter(w); err != errNotPooled {
+		t.Errorf("want %v; got %v", errNotPooled, err)
+	}
+}
@@ -2,6 +2,7 @@ package zlib
 
 import (
 	"bytes"
+	"errors"
 	"io"
 
 	"github.com/4kills/go-zlib/native"
@@ -14,12 +15,22 @@ type Reader struct {
 	inBuffer     *bytes.Buffer
 	outBuffer    *bytes.Buffer
 	eof          bool
+	auto         bool
+	container    Container
+	offset       int64
+	outOffset    int64
+	recovery     *recovery
+	multistream  bool
+	streamEnded  bool
+	stream       StreamInfo
+	limits       Limits
+	err          error
 }
 
 // Close closes the Reader by closing and freeing the underlying zlib stream.
 // You should not forget to call this after being done with the writer.
 func (r *Reader) Close() error {
-	if err := checkClosed(r.decompressor); err != nil {
+	if err := r.check(); err != nil {
 		return err
 	}
 	r.inBuffer = nil
@@ -38,10 +49,18 @@ func (r *Reader) ReadBuffer(compressed, out []byte) (n int, decompressed []byte,
 	if len(compressed) == 0 {
 		return 0, nil, errNoInput
 	}
-	if err := checkClosed(r.decompressor); err != nil {
+	if err := r.check(); err != nil {
 		return 0, nil, err
 	}
+	if r.auto {
+		if err := r.detect(compressed); err != nil {
+			return 0, nil, err
+		}
+	}
 
+	if r.limits.enabled() {
+		return r.readBufferLimited(compressed, out)
+	}
 	return r.decompressor.Decompress(compressed, out)
 }
 
@@ -52,9 +71,19 @@ func (r *Reader) Read(p []byte) (int, error) {
 	if len(p) == 0 {
 		return 0, io.ErrShortBuffer
 	}
-	if err := checkClosed(r.decompressor); err != nil {
+	if err := r.check(); err != nil {
 		return 0, err
 	}
+	for {
+		n, err := r.read(p)
+		// a recovering Reader keeps skipping corrupted data until it makes progress again
+		if n != 0 || err != nil || r.recovery == nil {
+			return n, err
+		}
+	}
+}
+
+func (r *Reader) read(p []byte) (int, error) {
 	if r.outBuffer.Len() == 0 && r.eof {
 		return 0, io.EOF
 	}
@@ -68,13 +97,61 @@ func (r *Reader) Read(p []byte) (int, error) {
 		return 0, err
 	}
 	r.inBuffer.Write(p[:n])
+	atEOF := err == io.EOF
+
+	if r.streamEnded {
+		if r.inBuffer.Len() == 0 {
+			if err == io.EOF {
+				r.eof = true
+				return 0, io.EOF
+			}
+			return 0, nil
+		}
+		if err := r.nextStream(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.recovery != nil && r.recovery.syncing {
+		if synced := r.sync(atEOF); !synced || r.inBuffer.Len() == 0 {
+			if r.eof {
+				return 0, io.EOF
+			}
+			return 0, nil
+		}
+	}
 
-	eof, processed, out, err := r.decompressor.DecompressStream(r.inBuffer.Bytes(), p)
+	if r.auto && r.container == ContainerUnknown {
+		if r.inBuffer.Len() < 2 && err != io.EOF {
+			return 0, nil
+		}
+		if err := r.detect(r.inBuffer.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+
+	eof, processed, out, err := r.decompress(r.inBuffer.Bytes(), p)
+	if eof && r.multistream {
+		// whether another stream follows is only known once there is more input
+		r.streamEnded = true
+		eof = false
+	}
 	r.eof = eof
+	r.inBuffer.Next(processed)
+	r.offset += int64(processed)
+	r.outOffset += int64(len(out))
+	if err == ErrOutputLimit || err == ErrRatioLimit {
+		return copy(p, out), err
+	}
 	if err != nil {
-		return 0, err
+		if r.recovery == nil || !errors.Is(err, ErrData) {
+			return 0, err
+		}
+		r.recovery.corrupted(r.offset, err)
+	} else if r.recovery != nil && atEOF && !r.eof && !r.streamEnded && processed == 0 && len(out) == 0 {
+		// without any progress on the remaining input the stream has been cut short
+		return 0, io.ErrUnexpectedEOF
 	}
-	r.inBuffer.Next(processed)
 
 	if r.eof && len(out) <= len(p) {
 		copy(p, out)
@@ -91,20 +168,94 @@ func (r *Reader) Read(p []byte) (int, error) {
 	return len(out), nil
 }
 
+// Clone returns an independent copy of the Reader in its current state, which reads the rest of the stream from reader.
+// Input the Reader has buffered but not decompressed yet and decompressed data not read yet are copied,
+// so reader has to continue where the underlying reader of the Reader stands.
+// This allows to branch a decompression and throw the branch away.
+// The copy owns its own native memory and has to be closed independently.
+// reader may be nil if you only plan on using ReadBuffer.
+func (r *Reader) Clone(reader io.Reader) (*Reader, error) {
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	c, err := r.decompressor.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:            reader,
+		decompressor: c,
+		inBuffer:     bytes.NewBuffer(append([]byte(nil), r.inBuffer.Bytes()...)),
+		outBuffer:    bytes.NewBuffer(append([]byte(nil), r.outBuffer.Bytes()...)),
+		eof:          r.eof,
+		auto:         r.auto,
+		container:    r.container,
+		offset:       r.offset,
+		outOffset:    r.outOffset,
+		recovery:     r.recovery.clone(),
+		multistream:  r.multistream,
+		streamEnded:  r.streamEnded,
+		stream:       r.stream,
+		limits:       r.limits,
+	}, nil
+}
+
+// InputOffset returns the number of bytes of compressed input Read has decompressed since the Reader
+// was created or Reset. Once Read returns io.EOF, it is the exact size of the stream (or streams) read.
+func (r *Reader) InputOffset() int64 {
+	return r.offset
+}
+
+// Remaining returns the input Read has taken from the underlying reader but not decompressed.
+// Once Read returns io.EOF, these are the bytes following the stream, so the caller may continue parsing
+// the underlying data after the stream with io.MultiReader(bytes.NewReader(r.Remaining()), underlying).
+// The slice is only valid until the next call of Read or Reset.
+func (r *Reader) Remaining() []byte {
+	if r.inBuffer == nil {
+		return nil
+	}
+	return r.inBuffer.Bytes()
+}
+
+// Prime inserts the lowest bits (1..16) of value into the input of the Reader, as if they preceded
+// the data that is yet to be read. This is meant for Readers created by NewRawReader, for example
+// to decompress a deflate stream starting in the middle of a byte: Prime the Reader with the
+// remaining bits of that byte and read from the byte following it.
+// The bits are inserted into the current stream only, so call Prime after Reset.
+func (r *Reader) Prime(bits, value int) error {
+	if bits < minPrimeBits || bits > maxPrimeBits {
+		return errInvalidPrimeBits
+	}
+	if err := r.check(); err != nil {
+		return err
+	}
+	return r.decompressor.Prime(bits, value)
+}
+
 // Reset resets the Reader to the state of being initialized with zlib.NewX(..),
-// but with the new underlying reader instead. It allows for reuse of the same reader.
-// AS OF NOW dict IS NOT USED. It's just there to implement the Resetter interface
-// to allow for easy interchangeability with the std lib. Just pass nil.
+// but with the new underlying reader and preset dictionary instead. It allows for reuse of the same reader.
+// dict may be nil if the streams to come do not require a preset dictionary.
 func (r *Reader) Reset(reader io.Reader, dict []byte) error {
-	if err := checkClosed(r.decompressor); err != nil {
+	if err := r.check(); err != nil {
 		return err
 	}
 
-	err := r.decompressor.Reset()
+	err := r.decompressor.ResetDict(dict)
+	if r.auto && err == nil {
+		err = r.decompressor.ResetWindow(defaultWindowBits + native.AutoWindowOffset)
+		r.container = ContainerUnknown
+	}
 
 	r.inBuffer = &bytes.Buffer{}
 	r.outBuffer = &bytes.Buffer{}
 	r.eof = false
+	r.offset = 0
+	r.outOffset = 0
+	r.streamEnded = false
+	r.stream = StreamInfo{}
+	if r.recovery != nil {
+		r.recovery = &recovery{onCorruption: r.recovery.onCorruption}
+	}
 	r.r = reader
 	return err
 }
@@ -112,15 +263,104 @@ func (r *Reader) Reset(reader io.Reader, dict []byte) error {
 // NewReader returns a new reader, reading from r. It decompresses read data.
 // r may be nil if you only plan on using ReadBuffer
 func NewReader(r io.Reader) (*Reader, error) {
-	c, err := native.NewDecompressor()
-	return &Reader{r, c, &bytes.Buffer{}, &bytes.Buffer{}, false}, err
+	return NewReaderDict(r, nil)
 }
 
-// NewReaderDict does exactly like NewReader as of NOW.
-// This will change once custom dicionaries are implemented.
-// This function has been added for compatibility with the std lib.
+// NewReaderDict performs like NewReader but uses a preset dictionary.
+// The dictionary is supplied whenever a stream asks for it, both by Read and ReadBuffer.
+// If a stream requires a dictionary that is missing or does not match dict,
+// the returned error is a *DictionaryError that matches ErrDictionary.
+// dict may be nil.
 func NewReaderDict(r io.Reader, dict []byte) (*Reader, error) {
-	return NewReader(r)
+	zr, err := newReader(r, defaultWindowBits, dict)
+	if err != nil {
+		return nil, err
+	}
+	zr.container = ContainerZlib
+	return zr, nil
+}
+
+// NewReaderOptions performs like NewReader but is configured by the given options, which are validated.
+// r may be nil if you only plan on using ReadBuffer.
+func NewReaderOptions(r io.Reader, opts ReaderOptions) (*Reader, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := newReader(r, opts.WindowBits, opts.Dict)
+	if err != nil {
+		return nil, err
+	}
+	zr.container = ContainerZlib
+	return zr, nil
+}
+
+// NewRawReader returns a new Reader decompressing raw DEFLATE (RFC 1951) data without the zlib header and trailer.
+// It may be used as a replacement for compress/flate.NewReader.
+// r may be nil if you only plan on using ReadBuffer.
+// If the underlying c stream cannot be allocated, for instance due to SetMemoryBudget, every method
+// of the returned Reader fails with that error. Use NewReaderOptions for the zlib format to get the error right away.
+func NewRawReader(r io.Reader) *Reader {
+	return NewRawReaderDict(r, nil)
+}
+
+// NewRawReaderDict performs like NewRawReader but uses a preset dictionary.
+// As raw DEFLATE carries no dictionary id, dict must be the very same dictionary that was used for compression.
+// It may be used as a replacement for compress/flate.NewReaderDict.
+func NewRawReaderDict(r io.Reader, dict []byte) *Reader {
+	zr, err := newReader(r, rawWindowBits, dict)
+	if err != nil {
+		return &Reader{r: r, container: ContainerRaw, err: err}
+	}
+	zr.container = ContainerRaw
+	return zr
+}
+
+// NewAutoReader returns a new Reader which detects whether the data is in the zlib, gzip or raw DEFLATE format.
+// zlib and gzip are recognized by their headers; anything else is decompressed as raw DEFLATE.
+// The detected format is reported by Container after the first Read or ReadBuffer.
+// Only single gzip members are read; use the gzip subpackage for multiple members and header access.
+// r may be nil if you only plan on using ReadBuffer.
+func NewAutoReader(r io.Reader) (*Reader, error) {
+	return NewAutoReaderDict(r, nil)
+}
+
+// NewAutoReaderDict performs like NewAutoReader but uses a preset dictionary for zlib and raw DEFLATE streams.
+func NewAutoReaderDict(r io.Reader, dict []byte) (*Reader, error) {
+	zr, err := newReader(r, defaultWindowBits+native.AutoWindowOffset, dict)
+	if err != nil {
+		return nil, err
+	}
+	zr.auto = true
+	return zr, nil
+}
+
+func newReader(r io.Reader, windowBits int, dict []byte) (*Reader, error) {
+	c, err := native.NewDecompressorWindow(windowBits, dict)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:            r,
+		decompressor: c,
+		inBuffer:     &bytes.Buffer{},
+		outBuffer:    &bytes.Buffer{},
+	}, nil
+}
+
+// Container returns the format of the stream being read. Readers created by NewAutoReader
+// report ContainerUnknown until the format has been detected by the first Read or ReadBuffer.
+func (r *Reader) Container() Container {
+	return r.container
+}
+
+// detect determines the container from the first bytes of the stream and switches to raw DEFLATE if needed
+func (r *Reader) detect(b []byte) error {
+	r.container = detectContainer(b)
+	if r.container == ContainerRaw {
+		return r.decompressor.ResetWindow(rawWindowBits)
+	}
+	return r.decompressor.ResetWindow(defaultWindowBits + native.AutoWindowOffset)
 }
 
 // Resetter resets the zlib.Reader returned by NewReader by assigning a new underyling reader,
@@ -131,3 +371,11 @@ type Resetter interface {
 	// but with the new underlying reader and dict instead. It allows for reuse of the same reader.
 	Reset(r io.Reader, dict []byte) error
 }
+
+// check returns the error the Reader could not be created with, or an error if it has been closed
+func (r *Reader) check() error {
+	if r.err != nil {
+		return r.err
+	}
+	return checkClosed(r.decompressor)
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"compress/zlib"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"testing"
 )
 
@@ -207,3 +209,55 @@ func benchmarkReadLevelGeneric(r io.ReadCloser, underlyingReader *bytes.Buffer,
 		}
 	}
 }
+
+// large stream benchmarks comparing InflateTo with Read
+
+func BenchmarkInflateTo1MB(b *testing.B) {
+	compressed, size := largeCompressedInput()
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		InflateTo(ioutil.Discard, bytes.NewReader(compressed))
+	}
+}
+
+func BenchmarkReadAll1MB(b *testing.B) {
+	compressed, size := largeCompressedInput()
+
+	r, _ := NewReader(nil)
+	defer r.Close()
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		r.Reset(bytes.NewReader(compressed), nil)
+		b.StartTimer()
+
+		io.Copy(ioutil.Discard, r)
+	}
+}
+
+func largeCompressedInput() ([]byte, int) {
+	input := xWords(1 << 20)
+
+	w := NewWriter(nil)
+	defer w.Close()
+	compressed, _ := w.WriteBuffer(input, nil)
+	return compressed, len(input)
+}
+
+// xWords returns n bytes of pseudo-random words, which compress about as well as ordinary text
+func xWords(n int) []byte {
+	words := [][]byte{[]byte("native "), []byte("zlib "), []byte("stream "), []byte("inflate "), []byte("window "), []byte("buffer\n")}
+	rnd := rand.New(rand.NewSource(1))
+
+	b := make([]byte, 0, n+16)
+	for len(b) < n {
+		b = append(b, words[rnd.Intn(len(words))]...)
+	}
+	return b[:n]
+}
@@ -2,9 +2,15 @@ package zlib
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
 	"compress/zlib"
+	"errors"
+	"hash/adler32"
 	"io"
+	"io/ioutil"
 	"testing"
+	"testing/iotest"
 )
 
 // UNIT TESTS
@@ -89,3 +95,315 @@ func TestRead_SmallBuffer(t *testing.T) {
 
 	sliceEquals(t, append(shortString, shortString...), out.Bytes())
 }
+
+func TestReadDict(t *testing.T) {
+	b := &bytes.Buffer{}
+	w, _ := zlib.NewWriterLevelDict(b, zlib.DefaultCompression, testDict)
+	w.Write(shortString)
+	w.Close()
+	compressed := b.Bytes()
+
+	r, err := NewReaderDict(bytes.NewReader(compressed), testDict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, shortString, act.Bytes())
+
+	if err := r.Reset(nil, testDict); err != nil {
+		t.Error(err)
+	}
+	_, out, err := r.ReadBuffer(compressed, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, shortString, out)
+}
+
+func TestReadDict_Missing(t *testing.T) {
+	b := &bytes.Buffer{}
+	w, _ := zlib.NewWriterLevelDict(b, zlib.DefaultCompression, testDict)
+	w.Write(shortString)
+	w.Close()
+
+	expected := adler32.Checksum(testDict)
+
+	for _, dict := range [][]byte{nil, []byte("some other dictionary")} {
+		r, err := NewReaderDict(bytes.NewReader(b.Bytes()), dict)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = io.Copy(ioutil.Discard, r)
+		if !errors.Is(err, ErrDictionary) {
+			t.Errorf("expected ErrDictionary: got %v", err)
+		}
+		var dictErr *DictionaryError
+		if !errors.As(err, &dictErr) || dictErr.ID != expected {
+			t.Errorf("expected dictionary id %x: got %v", expected, err)
+		}
+
+		if err := r.Reset(bytes.NewReader(b.Bytes()), testDict); err != nil {
+			t.Error(err)
+		}
+		_, out, err := r.ReadBuffer(b.Bytes(), nil)
+		if err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, shortString, out)
+		r.Close()
+	}
+}
+
+func TestRawRead(t *testing.T) {
+	b := &bytes.Buffer{}
+	w, _ := flate.NewWriter(b, flate.DefaultCompression)
+	w.Write(shortString)
+	w.Flush()
+	w.Write(shortString)
+	w.Close()
+	compressed := b.Bytes()
+
+	r := NewRawReader(bytes.NewReader(compressed))
+	defer r.Close()
+
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, append(shortString, shortString...), act.Bytes())
+
+	if err := r.Reset(nil, nil); err != nil {
+		t.Error(err)
+	}
+	_, out, err := r.ReadBuffer(compressed, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, append(shortString, shortString...), out)
+}
+
+func TestRawReadDict(t *testing.T) {
+	b := &bytes.Buffer{}
+	w, _ := flate.NewWriterDict(b, flate.BestCompression, testDict)
+	w.Write(shortString)
+	w.Close()
+
+	r := NewRawReaderDict(bytes.NewReader(b.Bytes()), testDict)
+	defer r.Close()
+
+	for i := 0; i < 2; i++ {
+		act := &bytes.Buffer{}
+		if _, err := io.Copy(act, r); err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, shortString, act.Bytes())
+
+		if err := r.Reset(bytes.NewReader(b.Bytes()), testDict); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestAutoReader(t *testing.T) {
+	zb, gb, fb := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	zw := zlib.NewWriter(zb)
+	gw := gzip.NewWriter(gb)
+	fw, _ := flate.NewWriter(fb, flate.DefaultCompression)
+	for _, w := range []io.WriteCloser{zw, gw, fw} {
+		w.Write(shortString)
+		w.Close()
+	}
+
+	cases := []struct {
+		compressed []byte
+		container  Container
+	}{
+		{zb.Bytes(), ContainerZlib},
+		{gb.Bytes(), ContainerGzip},
+		{fb.Bytes(), ContainerRaw},
+	}
+
+	r, err := NewAutoReader(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, c := range cases {
+		if err := r.Reset(bytes.NewReader(c.compressed), nil); err != nil {
+			t.Fatal(err)
+		}
+		if r.Container() != ContainerUnknown {
+			t.Errorf("container must be unknown before reading: got %v", r.Container())
+		}
+
+		act := &bytes.Buffer{}
+		if _, err := io.Copy(act, r); err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, shortString, act.Bytes())
+		if r.Container() != c.container {
+			t.Errorf("wrong container detected: want %v; got %v", c.container, r.Container())
+		}
+
+		_, out, err := r.ReadBuffer(c.compressed, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, shortString, out)
+		if r.Container() != c.container {
+			t.Errorf("wrong container detected: want %v; got %v", c.container, r.Container())
+		}
+	}
+}
+
+func TestAutoReader_OneByteReads(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(shortString)
+	w.Close()
+
+	r, err := NewAutoReader(iotest.OneByteReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, shortString, act)
+	if r.Container() != ContainerZlib {
+		t.Errorf("wrong container detected: want %v; got %v", ContainerZlib, r.Container())
+	}
+}
+
+func TestReadPrime(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w, err := NewRawWriter(b, DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Prime(3, 5)
+	w.Write(longString)
+	w.Close()
+	compressed := b.Bytes()
+
+	// start in the middle of the first byte, right after the 3 primed bits
+	r := NewRawReader(bytes.NewReader(compressed[1:]))
+	defer r.Close()
+	if err := r.Prime(5, int(compressed[0]>>3)); err != nil {
+		t.Fatal(err)
+	}
+
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, longString, act.Bytes())
+
+	if err := r.Reset(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Prime(5, int(compressed[0]>>3)); err != nil {
+		t.Fatal(err)
+	}
+	_, out, err := r.ReadBuffer(compressed[1:], nil)
+	if err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, longString, out)
+
+	if err := r.Prime(17, 0); err != errInvalidPrimeBits {
+		t.Errorf("expected %v; got %v", errInvalidPrimeBits, err)
+	}
+}
+
+func TestReaderClone(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(longString)
+	w.Close()
+	compressed := b.Bytes()
+
+	in := bytes.NewReader(compressed)
+	r, err := NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	head := make([]byte, len(longString)/2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		t.Fatal(err)
+	}
+
+	clone, err := r.Clone(bytes.NewReader(compressed[len(compressed)-in.Len():]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	branch := &bytes.Buffer{}
+	if _, err := io.Copy(branch, clone); err != nil {
+		t.Error(err)
+	}
+	if err := clone.Close(); err != nil {
+		t.Error(err)
+	}
+
+	rest := &bytes.Buffer{}
+	if _, err := io.Copy(rest, r); err != nil {
+		t.Error(err)
+	}
+
+	sliceEquals(t, longString[len(head):], branch.Bytes())
+	sliceEquals(t, longString[len(head):], rest.Bytes())
+	sliceEquals(t, longString[:len(head)], head)
+}
+
+func TestReaderRemaining(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w := zlib.NewWriter(b)
+	w.Write(longString)
+	w.Close()
+	size := int64(b.Len())
+	trailing := []byte("data following the stream")
+	b.Write(trailing)
+	compressed := b.Bytes()
+
+	for _, src := range []io.Reader{bytes.NewReader(compressed), iotest.OneByteReader(bytes.NewReader(compressed))} {
+		r, err := NewReader(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sliceEquals(t, longString, act)
+
+		if r.InputOffset() != size {
+			t.Errorf("wrong input offset: want %d; got %d", size, r.InputOffset())
+		}
+		rest, err := ioutil.ReadAll(io.MultiReader(bytes.NewReader(r.Remaining()), src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sliceEquals(t, trailing, rest)
+		r.Close()
+	}
+}
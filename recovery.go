@@ -0,0 +1,89 @@
+package zlib
+
+import "io"
+
+// Corruption describes a range of compressed input that a recovering Reader skipped due to corrupted data.
+type Corruption struct {
+	// Start is the offset in the compressed input at which the corruption was detected.
+	// Data decompressed shortly before may be corrupted as well, as corruption is not always detected right away.
+	Start int64
+	// End is the offset in the compressed input at which decompression resumed after the next full flush point
+	// or the end of the input if there was none.
+	End int64
+	// Err is the error that was caused by the corrupted data.
+	Err error
+}
+
+type recovery struct {
+	onCorruption func(Corruption)
+	corruptions  []Corruption
+	syncing      bool
+	start        int64
+	err          error
+}
+
+// NewRecoveringReader performs like NewReader but recovers from corrupted data instead of failing:
+// Read skips the compressed input up to the next full flush point (see FullFlush) and continues decompressing
+// from there. Everything in between is lost. Each skipped range is passed to onCorruption, which may be nil,
+// and is available via Corruptions. Writers should flush with FullFlush periodically for this to be of use.
+// As the check value cannot be verified after a corruption, only Read recovers, while ReadBuffer fails as usual.
+// r may be nil if you only plan on using ReadBuffer.
+func NewRecoveringReader(r io.Reader, onCorruption func(Corruption)) (*Reader, error) {
+	zr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	zr.recovery = &recovery{onCorruption: onCorruption}
+	return zr, nil
+}
+
+// Corruptions returns the ranges of compressed input skipped since the Reader was created or Reset
+// by a Reader created by NewRecoveringReader.
+func (r *Reader) Corruptions() []Corruption {
+	if r.recovery == nil {
+		return nil
+	}
+	return r.recovery.corruptions
+}
+
+// sync skips the buffered input up to the next full flush point and returns whether it has been found.
+// If the input ends without any, the rest of the input is lost and the Reader reaches EOF.
+func (r *Reader) sync(atEOF bool) bool {
+	n, found, err := r.decompressor.Sync(r.inBuffer.Bytes())
+	r.inBuffer.Next(n)
+	r.offset += int64(n)
+
+	if !found && err == nil && !atEOF {
+		return false
+	}
+
+	r.recovery.report(r.offset)
+	if !found {
+		r.eof = true
+	}
+	return found
+}
+
+func (rc *recovery) corrupted(offset int64, err error) {
+	rc.syncing = true
+	rc.start = offset
+	rc.err = err
+}
+
+func (rc *recovery) report(end int64) {
+	c := Corruption{Start: rc.start, End: end, Err: rc.err}
+	rc.corruptions = append(rc.corruptions, c)
+	rc.syncing = false
+	if rc.onCorruption != nil {
+		rc.onCorruption(c)
+	}
+}
+
+func (rc *recovery) clone() *recovery {
+	if rc == nil {
+		return nil
+	}
+	clone := *rc
+	clone.corruptions = append([]Corruption(nil), rc.corruptions...)
+	return &clone
+}
@@ -0,0 +1,187 @@
+package zlib
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+)
+
+func recoveryTestData(t *testing.T) ([][]byte, []byte, int) {
+	makeLongString()
+
+	chunks := [][]byte{
+		longString[:4096],
+		bytes.Repeat([]byte("the corrupted chunk "), 200),
+		longString[4096:8192],
+		longString[8192:],
+	}
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	corruptAt := 0
+	for i, chunk := range chunks {
+		w.Write(chunk)
+		if err := w.FlushMode(FullFlush); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			corruptAt = b.Len() + 10
+		}
+	}
+	w.Close()
+
+	compressed := b.Bytes()
+	for i := corruptAt; i < corruptAt+16; i++ {
+		compressed[i] = 0xff
+	}
+	return chunks, compressed, corruptAt
+}
+
+func TestRecoveringReader(t *testing.T) {
+	chunks, compressed, corruptAt := recoveryTestData(t)
+
+	var reported []Corruption
+	r, err := NewRecoveringReader(bytes.NewReader(compressed), func(c Corruption) {
+		reported = append(reported, c)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(act.Bytes(), chunks[0]) {
+		t.Error("data before the corruption is missing")
+	}
+	if !bytes.HasSuffix(act.Bytes(), append(append([]byte{}, chunks[2]...), chunks[3]...)) {
+		t.Error("data after the corruption is missing")
+	}
+
+	if len(reported) != 1 {
+		t.Fatalf("expected 1 corruption; got %d", len(reported))
+	}
+	c := reported[0]
+	if c.Start < int64(corruptAt) || c.End <= c.Start || c.End > int64(len(compressed)) {
+		t.Errorf("unexpected corrupted range [%d, %d) for corruption at %d", c.Start, c.End, corruptAt)
+	}
+	if !errors.Is(c.Err, ErrData) {
+		t.Errorf("expected %v; got %v", ErrData, c.Err)
+	}
+	if corruptions := r.Corruptions(); len(corruptions) != 1 || corruptions[0] != c {
+		t.Errorf("expected %v; got %v", reported, corruptions)
+	}
+
+	if err := r.Reset(bytes.NewReader(compressed), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Corruptions()) != 0 {
+		t.Error("corruptions not cleared by Reset")
+	}
+}
+
+func TestRecoveringReader_NoFlushPoint(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Write(longString)
+	w.Close()
+	compressed := b.Bytes()
+	for i := 20; i < 36; i++ {
+		compressed[i] = 0xff
+	}
+
+	r, err := NewRecoveringReader(bytes.NewReader(compressed), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	corruptions := r.Corruptions()
+	if len(corruptions) != 1 || corruptions[0].End != int64(len(compressed)) {
+		t.Errorf("expected the rest of the input to be lost; got %v", corruptions)
+	}
+}
+
+func TestReader_Corrupted(t *testing.T) {
+	_, compressed, _ := recoveryTestData(t)
+
+	r, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := io.Copy(ioutil.Discard, r); !errors.Is(err, ErrData) {
+		t.Errorf("expected %v; got %v", ErrData, err)
+	}
+}
+
+func TestRecoveringReader_OneByteReads(t *testing.T) {
+	chunks, compressed, _ := recoveryTestData(t)
+
+	r, err := NewRecoveringReader(iotest.OneByteReader(bytes.NewReader(compressed)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(act.Bytes(), append(append([]byte{}, chunks[2]...), chunks[3]...)) {
+		t.Error("data after the corruption is missing")
+	}
+	if len(r.Corruptions()) != 1 {
+		t.Errorf("expected 1 corruption; got %v", r.Corruptions())
+	}
+}
+
+func TestRecoveringReader_NoEmptyReads(t *testing.T) {
+	_, compressed, _ := recoveryTestData(t)
+
+	r, err := NewRecoveringReader(iotest.OneByteReader(bytes.NewReader(compressed)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	p := make([]byte, 1024)
+	for {
+		n, err := r.Read(p)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			t.Fatal("Read returned neither data nor an error")
+		}
+	}
+}
+
+func TestRecoveringReader_Truncated(t *testing.T) {
+	_, compressed, _ := recoveryTestData(t)
+
+	r, err := NewRecoveringReader(bytes.NewReader(compressed[:len(compressed)-10]), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := io.Copy(ioutil.Discard, r); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected %v; got %v", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,213 @@
+package zlib
+
+import (
+	"io"
+	"sync"
+
+	"github.com/4kills/go-zlib/native"
+)
+
+// SeekableReader provides random access to the uncompressed data of a compressed stream stored in an io.ReaderAt
+// by starting decompression at the closest access point of an Index built for the stream.
+// Reading sequentially continues decompression where the previous read stopped.
+// It implements io.Reader, io.ReaderAt and io.Seeker; ReadAt may be called concurrently, but is serialized.
+type SeekableReader struct {
+	src          io.ReaderAt
+	index        *Index
+	decompressor *native.Decompressor
+	offset       int64
+
+	mu      sync.Mutex
+	in      []byte
+	out     []byte
+	pending []byte // compressed input read from src but not decompressed yet
+	rest    []byte // decompressed data not read yet
+	inPos   int64  // offset in src following the pending input
+	outPos  int64  // uncompressed offset of rest or -1 if decompression has to start at an access point
+	srcEOF  bool
+	end     bool
+}
+
+// NewSeekableReader returns a new SeekableReader reading the compressed stream described by index from src.
+// src must hold the very stream the index has been built for, starting at offset 0.
+func NewSeekableReader(src io.ReaderAt, index *Index) (*SeekableReader, error) {
+	if index == nil {
+		return nil, errInvalidIndex
+	}
+	if err := index.validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := native.NewDecompressorWindow(rawWindowBits, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SeekableReader{
+		src:          src,
+		index:        index,
+		decompressor: c,
+		in:           make([]byte, indexBufferSize),
+		out:          make([]byte, indexBufferSize),
+		outPos:       -1,
+	}, nil
+}
+
+// Size returns the size of the uncompressed data.
+func (s *SeekableReader) Size() int64 {
+	return s.index.Size
+}
+
+// Close closes the SeekableReader by closing and freeing the underlying zlib stream.
+// It does not close src.
+func (s *SeekableReader) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkClosed(s.decompressor); err != nil {
+		return err
+	}
+	s.pending = nil
+	return s.decompressor.Close()
+}
+
+// Read reads uncompressed data from the current offset into p and advances the offset accordingly.
+func (s *SeekableReader) Read(p []byte) (int, error) {
+	n, err := s.ReadAt(p, s.offset)
+	s.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the offset for the next Read in the uncompressed data as io.Seeker describes it.
+// Seeking past the end is allowed, in which case Read returns io.EOF.
+func (s *SeekableReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.index.Size
+	default:
+		return 0, errInvalidWhence
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	s.offset = offset
+	return offset, nil
+}
+
+// ReadAt reads len(p) bytes of uncompressed data starting at offset off into p as io.ReaderAt describes it.
+// It does not affect the offset used by Read.
+func (s *SeekableReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkClosed(s.decompressor); err != nil {
+		return 0, err
+	}
+	if off >= s.index.Size {
+		return 0, io.EOF
+	}
+
+	// continue decompressing unless off is behind or an access point is closer
+	if s.outPos < 0 || off < s.outPos || (off-s.outPos > indexBufferSize && s.index.point(off).Out > s.outPos) {
+		if err := s.start(s.index.point(off)); err != nil {
+			s.outPos = -1
+			return 0, err
+		}
+	}
+
+	n, err := s.decompress(p, off-s.outPos)
+	if err != nil {
+		s.outPos = -1
+		return n, err
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// start prepares the decompressor to decompress from the given access point
+func (s *SeekableReader) start(point *AccessPoint) error {
+	if err := s.decompressor.ResetDict(point.Window); err != nil {
+		return err
+	}
+	s.pending = nil
+	s.rest = nil
+	s.inPos = point.In
+	s.outPos = point.Out
+	s.srcEOF = false
+	s.end = false
+
+	if point.Bits == 0 {
+		return nil
+	}
+	if _, err := s.src.ReadAt(s.in[:1], point.In-1); err != nil && err != io.EOF {
+		return err
+	}
+	return s.decompressor.Prime(point.Bits, int(s.in[0])>>uint(8-point.Bits))
+}
+
+// decompress skips the next skip bytes of uncompressed data and decompresses the following data into p
+func (s *SeekableReader) decompress(p []byte, skip int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.rest) != 0 {
+			skipped := len(s.rest)
+			if int64(skipped) > skip {
+				skipped = int(skip)
+			}
+			copied := copy(p[n:], s.rest[skipped:])
+			s.rest = s.rest[skipped+copied:]
+			s.outPos += int64(skipped + copied)
+			skip -= int64(skipped)
+			n += copied
+			continue
+		}
+		if s.end || s.outPos >= s.index.Size {
+			break
+		}
+
+		if len(s.pending) == 0 {
+			if s.srcEOF {
+				return n, io.ErrUnexpectedEOF
+			}
+			m, err := s.src.ReadAt(s.in, s.inPos)
+			if err != nil && err != io.EOF {
+				return n, err
+			}
+			s.srcEOF = err == io.EOF
+			s.pending = s.in[:m]
+			s.inPos += int64(m)
+			continue
+		}
+
+		// decompress directly into p once there is nothing left to skip
+		out := s.out
+		if skip == 0 {
+			out = p[n:]
+		}
+		consumed, produced, end, err := s.decompressor.DecompressBlock(s.pending, out)
+		if err != nil {
+			return n, err
+		}
+		s.pending = s.pending[consumed:]
+		s.end = end
+
+		if skip == 0 {
+			s.outPos += int64(produced)
+			n += produced
+		} else {
+			s.rest = s.out[:produced]
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,22 @@
+package zlib
+
+import "github.com/4kills/go-zlib/native"
+
+// LiveStream describes a native stream that has been allocated while tracking was enabled and not freed yet.
+type LiveStream = native.LiveStream
+
+// TrackStreams enables or disables the registry of live native streams of Writers and Readers, including those
+// of the subpackages, which records the allocation stack of every stream. It is meant for catching leaks in tests,
+// as recording the stack slows down the allocation of streams. Disabling it forgets the streams recorded so far.
+// Streams that become unreachable without being closed are freed by a finalizer in any case,
+// but only once the garbage collector gets to them.
+func TrackStreams(enabled bool) {
+	native.TrackStreams(enabled)
+}
+
+// LiveStreams returns the native streams allocated since tracking was enabled that have not been closed yet.
+// Streams freed by the finalizer are not included.
+// The streams of InflateTo and the buffers of gzip headers are not recorded in the registry.
+func LiveStreams() []LiveStream {
+	return native.LiveStreams()
+}
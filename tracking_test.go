@@ -0,0 +1,81 @@
+package zlib
+
+import (
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackStreams(t *testing.T) {
+	TrackStreams(true)
+	defer TrackStreams(false)
+
+	w := NewWriter(nil)
+	r, err := NewReader(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streams := LiveStreams()
+	if len(streams) != 2 {
+		t.Fatalf("want 2 live streams; got %d", len(streams))
+	}
+	for _, s := range streams {
+		if !strings.Contains(s.Stack, "TestTrackStreams") {
+			t.Errorf("the allocation stack does not lead to the test:\n%s", s.Stack)
+		}
+	}
+
+	w.Close()
+	r.Close()
+	if streams := LiveStreams(); len(streams) != 0 {
+		t.Errorf("want no live streams; got %d", len(streams))
+	}
+}
+
+func TestTrackStreams_Disabled(t *testing.T) {
+	w := NewWriter(nil)
+	defer w.Close()
+
+	TrackStreams(true)
+	defer TrackStreams(false)
+	if streams := LiveStreams(); len(streams) != 0 {
+		t.Errorf("streams allocated before enabling tracking are recorded: %d", len(streams))
+	}
+}
+
+func TestTrackStreams_Twice(t *testing.T) {
+	TrackStreams(true)
+	defer TrackStreams(false)
+
+	w := NewWriter(nil)
+	defer w.Close()
+
+	TrackStreams(true)
+	if streams := LiveStreams(); len(streams) != 1 {
+		t.Errorf("enabling tracking again forgot the recorded streams: want 1; got %d", len(streams))
+	}
+}
+
+func TestFinalizer(t *testing.T) {
+	TrackStreams(true)
+	defer TrackStreams(false)
+
+	// leaked without Close
+	func() {
+		w := NewWriter(ioutil.Discard)
+		w.Write(shortString)
+		r, _ := NewReader(nil)
+		r.ReadBuffer(shortString, nil)
+	}()
+
+	for i := 0; len(LiveStreams()) != 0; i++ {
+		if i == 100 {
+			t.Fatalf("the finalizer has not freed %d leaked streams", len(LiveStreams()))
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -12,6 +12,12 @@ const (
 
 	minStrategy = 0
 	maxStrategy = 4
+
+	minPrimeBits = 1
+	maxPrimeBits = 16
+
+	defaultWindowBits = 15
+	rawWindowBits     = -defaultWindowBits
 )
 
 // Writer compresses and writes given data to an underlying io.Writer
@@ -20,16 +26,17 @@ type Writer struct {
 	level      int
 	strategy   int
 	compressor *native.Compressor
+	err        error
 }
 
 // NewWriter returns a new Writer with the underlying io.Writer to compress to.
 // w may be nil if you only plan on using WriteBuffer.
-// Panics if the underlying c stream cannot be allocated which would indicate a severe error
-// not only for this library but also for the rest of your code.
+// If the underlying c stream cannot be allocated, for instance due to SetMemoryBudget, every method
+// of the returned Writer fails with that error (and Reset panics). Use NewWriterLevel to get the error right away.
 func NewWriter(w io.Writer) *Writer {
 	zw, err := NewWriterLevel(w, DefaultCompression)
 	if err != nil {
-		panic(err)
+		return &Writer{w: w, level: DefaultCompression, err: err}
 	}
 	return zw
 }
@@ -40,31 +47,68 @@ func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
 	return NewWriterLevelStrategy(w, level, DefaultStrategy)
 }
 
-// NewWriterLevelDict does exactly like NewWriterLevel as of NOW.
-// This will change once custom dicionaries are implemented.
-// This function has been added for compatibility with the std lib.
+// NewWriterLevelDict performs like NewWriterLevel but uses a preset dictionary.
+// The dictionary may be nil. If not, its contents should not be modified until the Writer is closed.
+// The compressed data can only be decompressed by a Reader with the same dictionary.
+// w may be nil if you only plan on using WriteBuffer.
 func NewWriterLevelDict(w io.Writer, level int, dict []byte) (*Writer, error) {
-	return NewWriterLevel(w, level)
+	return NewWriterLevelStrategyDict(w, level, DefaultStrategy, dict)
 }
 
-// NewWriterLevelStrategyDict does exactly like NewWriterLevelStrategy as of NOW.
-// This will change once custom dicionaries are implemented.
-// This function has been added mainly for completeness' sake.
+// NewWriterLevelStrategyDict performs like NewWriterLevelStrategy but uses a preset dictionary.
+// The dictionary may be nil. If not, its contents should not be modified until the Writer is closed.
+// The dictionary is kept across Reset and every call of WriteBuffer.
+// w may be nil if you only plan on using WriteBuffer.
 func NewWriterLevelStrategyDict(w io.Writer, level, strategy int, dict []byte) (*Writer, error) {
-	return NewWriterLevelStrategy(w, level, strategy)
+	return newWriter(w, level, strategy, defaultWindowBits, dict)
 }
 
 // NewWriterLevelStrategy performs like NewWriter but you may also specify the compression level and strategy.
 // w may be nil if you only plan on using WriteBuffer.
 func NewWriterLevelStrategy(w io.Writer, level, strategy int) (*Writer, error) {
+	return NewWriterLevelStrategyDict(w, level, strategy, nil)
+}
+
+// NewRawWriter returns a new Writer compressing to raw DEFLATE (RFC 1951) without the zlib header and trailer.
+// It may be used as a replacement for compress/flate.NewWriter.
+// w may be nil if you only plan on using WriteBuffer.
+func NewRawWriter(w io.Writer, level int) (*Writer, error) {
+	return NewRawWriterDict(w, level, nil)
+}
+
+// NewRawWriterDict performs like NewRawWriter but uses a preset dictionary.
+// As raw DEFLATE carries no dictionary id, the data must be decompressed with the very same dictionary.
+// It may be used as a replacement for compress/flate.NewWriterDict.
+func NewRawWriterDict(w io.Writer, level int, dict []byte) (*Writer, error) {
+	return newWriter(w, level, DefaultStrategy, rawWindowBits, dict)
+}
+
+// NewWriterOptions performs like NewWriter but is configured by the given options, which are validated.
+// w may be nil if you only plan on using WriteBuffer.
+func NewWriterOptions(w io.Writer, opts Options) (*Writer, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	c, err := native.NewCompressorMemLevel(opts.Level, opts.Strategy, opts.WindowBits, opts.MemLevel, opts.Dict)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, level: opts.Level, strategy: opts.Strategy, compressor: c}, nil
+}
+
+func newWriter(w io.Writer, level, strategy, windowBits int, dict []byte) (*Writer, error) {
 	if level != DefaultCompression && (level < minCompression || level > maxCompression) {
 		return nil, errInvalidLevel
 	}
 	if strategy < minStrategy || strategy > maxStrategy {
 		return nil, errInvalidStrategy
 	}
-	c, err := native.NewCompressorStrategy(level, strategy)
-	return &Writer{w, level, strategy, c}, err
+	c, err := native.NewCompressorWindow(level, strategy, windowBits, dict)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, level: level, strategy: strategy, compressor: c}, nil
 }
 
 // WriteBuffer takes uncompressed data in, compresses it to out and returns out sliced accordingly.
@@ -76,7 +120,7 @@ func (zw *Writer) WriteBuffer(in, out []byte) ([]byte, error) {
 	if len(in) == 0 {
 		return nil, errNoInput
 	}
-	if err := checkClosed(zw.compressor); err != nil {
+	if err := zw.check(); err != nil {
 		return nil, err
 	}
 
@@ -100,7 +144,7 @@ func (zw *Writer) Write(p []byte) (int, error) {
 	if len(p) == 0 {
 		return -1, errNoInput
 	}
-	if err := checkClosed(zw.compressor); err != nil {
+	if err := zw.check(); err != nil {
 		return -1, err
 	}
 
@@ -123,7 +167,7 @@ func (zw *Writer) Write(p []byte) (int, error) {
 // Close closes the writer by flushing any unwritten data to the underlying writer.
 // You should not forget to call this after being done with the writer.
 func (zw *Writer) Close() error {
-	if err := checkClosed(zw.compressor); err != nil {
+	if err := zw.check(); err != nil {
 		return err
 	}
 
@@ -140,23 +184,101 @@ func (zw *Writer) Close() error {
 	return err
 }
 
-// Flush writes compressed buffered data to the underlying writer.
+// Flush writes compressed buffered data to the underlying writer using SyncFlush.
 func (zw *Writer) Flush() error {
-	if err := checkClosed(zw.compressor); err != nil {
+	return zw.FlushMode(SyncFlush)
+}
+
+// FlushMode writes compressed buffered data to the underlying writer using the given flush mode,
+// which is one of PartialFlush, SyncFlush, FullFlush and BlockFlush.
+func (zw *Writer) FlushMode(mode int) error {
+	if mode != PartialFlush && mode != SyncFlush && mode != FullFlush && mode != BlockFlush {
+		return errInvalidFlushMode
+	}
+	if err := zw.check(); err != nil {
+		return err
+	}
+
+	b, err := zw.compressor.FlushMode(mode)
+	if err != nil {
+		return err
+	}
+	if zw.w == nil {
+		return nil
+	}
+	_, err = zw.w.Write(b)
+	return err
+}
+
+// SetParams changes the compression level and strategy for the data written from now on,
+// without starting a new zlib stream. Data written before is compressed with the previous
+// level and strategy and written to the underlying writer first.
+// This allows to switch to HuffmanOnly or NoCompression for already compressed sections, for instance.
+// The new level and strategy are kept across Reset.
+func (zw *Writer) SetParams(level, strategy int) error {
+	if level != DefaultCompression && (level < minCompression || level > maxCompression) {
+		return errInvalidLevel
+	}
+	if strategy < minStrategy || strategy > maxStrategy {
+		return errInvalidStrategy
+	}
+	if err := zw.check(); err != nil {
+		return err
+	}
+
+	b, err := zw.compressor.SetParams(level, strategy)
+	if err != nil {
 		return err
 	}
-	b, _ := zw.compressor.Flush()
-	_, err := zw.w.Write(b)
+	zw.level = level
+	zw.strategy = strategy
+
+	if zw.w == nil || len(b) == 0 {
+		return nil
+	}
+	_, err = zw.w.Write(b)
 	return err
 }
 
+// Clone returns an independent copy of the Writer, which continues the current stream exactly where
+// the Writer stands and writes to w. Compressed data that has already been written to the underlying writer
+// of zw is not repeated by the copy, so the output of the copy has to be appended to it.
+// This allows to compress a common prefix only once and fork the stream for every message following it.
+// The copy owns its own native memory and has to be closed independently.
+// w may be nil if you only plan on using WriteBuffer.
+func (zw *Writer) Clone(w io.Writer) (*Writer, error) {
+	if err := zw.check(); err != nil {
+		return nil, err
+	}
+	c, err := zw.compressor.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, level: zw.level, strategy: zw.strategy, compressor: c}, nil
+}
+
+// Prime inserts the lowest bits (1..16) of value into the compressed output before any data written from now on.
+// This is meant for Writers created by NewRawWriter, to rebuild streams bit-exactly, for example
+// when appending to a deflate stream that ends in the middle of a byte. On a zlib Writer
+// the bits land after the header, in front of the first block, so the output is no longer a valid stream.
+// The bits are inserted into the current stream only and are not kept across Reset.
+func (zw *Writer) Prime(bits, value int) error {
+	if bits < minPrimeBits || bits > maxPrimeBits {
+		return errInvalidPrimeBits
+	}
+	if err := zw.check(); err != nil {
+		return err
+	}
+	return zw.compressor.Prime(bits, value)
+}
+
 // Reset flushes the buffered data to the current underyling writer,
 // resets the Writer to the state of being initialized with zlib.NewX(..),
 // but with the new underlying writer instead.
-// This will panic if the writer has already been closed, writer could not be reset or could not write to current
-// underlying writer.
+// This will panic if the writer could not be created, has already been closed, could not be reset
+// or could not write to the current underlying writer.
 func (zw *Writer) Reset(w io.Writer) {
-	if err := checkClosed(zw.compressor); err != nil {
+	if err := zw.check(); err != nil {
 		panic(err)
 	}
 
@@ -176,3 +298,11 @@ func (zw *Writer) Reset(w io.Writer) {
 
 	zw.w = w
 }
+
+// check returns the error the Writer could not be created with, or an error if it has been closed
+func (zw *Writer) check() error {
+	if zw.err != nil {
+		return zw.err
+	}
+	return checkClosed(zw.compressor)
+}
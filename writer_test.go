@@ -2,6 +2,7 @@ package zlib
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/zlib"
 	"io"
 	"testing"
@@ -67,3 +68,314 @@ func TestReset(t *testing.T) {
 
 	sliceEquals(t, append(shortString, shortString...), act.Bytes())
 }
+
+var testDict = []byte("hello, world\nhello")
+
+func TestWriteDict(t *testing.T) {
+	b := &bytes.Buffer{}
+	w, err := NewWriterLevelDict(b, DefaultCompression, testDict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write(shortString)
+	if err != nil {
+		t.Error(err)
+	}
+	w.Reset(b)
+	_, err = w.Write(shortString)
+	if err != nil {
+		t.Error(err)
+	}
+	w.Close()
+
+	for i := 0; i < 2; i++ {
+		r, err := zlib.NewReaderDict(b, testDict)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act := &bytes.Buffer{}
+		_, err = io.Copy(act, r)
+		if err != nil {
+			t.Error(err)
+		}
+
+		sliceEquals(t, shortString, act.Bytes())
+	}
+}
+
+func TestWriteBufferDict(t *testing.T) {
+	w, err := NewWriterLevelStrategyDict(nil, BestCompression, DefaultStrategy, testDict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 2; i++ {
+		b, err := w.WriteBuffer(shortString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := zlib.NewReader(bytes.NewReader(b)); err != zlib.ErrDictionary {
+			t.Errorf("expected dictionary to be required: got %v", err)
+		}
+
+		r, err := zlib.NewReaderDict(bytes.NewReader(b), testDict)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act := &bytes.Buffer{}
+		_, err = io.Copy(act, r)
+		if err != nil {
+			t.Error(err)
+		}
+
+		sliceEquals(t, shortString, act.Bytes())
+	}
+}
+
+func TestRawWrite(t *testing.T) {
+	b := &bytes.Buffer{}
+	w, err := NewRawWriter(b, DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write(shortString)
+	if err != nil {
+		t.Error(err)
+	}
+	w.Flush()
+	_, err = w.Write(shortString)
+	if err != nil {
+		t.Error(err)
+	}
+	w.Close()
+
+	r := flate.NewReader(b)
+	act := &bytes.Buffer{}
+	_, err = io.Copy(act, r)
+	if err != nil {
+		t.Error(err)
+	}
+
+	sliceEquals(t, append(shortString, shortString...), act.Bytes())
+}
+
+func TestRawWriteBufferDict(t *testing.T) {
+	w, err := NewRawWriterDict(nil, BestSpeed, testDict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 2; i++ {
+		b, err := w.WriteBuffer(shortString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := flate.NewReaderDict(bytes.NewReader(b), testDict)
+		act := &bytes.Buffer{}
+		_, err = io.Copy(act, r)
+		if err != nil {
+			t.Error(err)
+		}
+
+		sliceEquals(t, shortString, act.Bytes())
+	}
+}
+
+func TestSetParams(t *testing.T) {
+	makeLongString()
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+
+	if _, err := w.Write(longString); err != nil {
+		t.Error(err)
+	}
+	before := b.Len()
+	if err := w.SetParams(NoCompression, DefaultStrategy); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() <= before {
+		t.Error("pending data was not written before switching the parameters")
+	}
+
+	before = b.Len()
+	if _, err := w.Write(longString); err != nil {
+		t.Error(err)
+	}
+	if err := w.SetParams(DefaultCompression, HuffmanOnly); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len()-before < len(longString) {
+		t.Errorf("data was compressed although compression was disabled: %d < %d", b.Len()-before, len(longString))
+	}
+
+	if _, err := w.Write(longString); err != nil {
+		t.Error(err)
+	}
+	if err := w.SetParams(DefaultCompression, DefaultStrategy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(longString); err != nil {
+		t.Error(err)
+	}
+	w.Close()
+
+	r, err := zlib.NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, bytes.Repeat(longString, 4), act.Bytes())
+}
+
+func TestSetParams_BeforeWriteBuffer(t *testing.T) {
+	w := NewWriter(nil)
+	defer w.Close()
+
+	if err := w.SetParams(BestSpeed, RLE); err != nil {
+		t.Fatal(err)
+	}
+	b, err := w.WriteBuffer(shortString, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := testReadBytes(bytes.NewBuffer(b), t)
+	sliceEquals(t, shortString, out)
+
+	if err := w.SetParams(maxCompression+1, DefaultStrategy); err != errInvalidLevel {
+		t.Errorf("expected %v; got %v", errInvalidLevel, err)
+	}
+}
+
+func TestFlushMode(t *testing.T) {
+	makeLongString()
+
+	for _, mode := range []int{PartialFlush, SyncFlush, FullFlush, BlockFlush} {
+		b := &bytes.Buffer{}
+		w := NewWriter(b)
+
+		for i := 0; i < 3; i++ {
+			if _, err := w.Write(longString); err != nil {
+				t.Error(err)
+			}
+			if err := w.FlushMode(mode); err != nil {
+				t.Errorf("mode %d: %v", mode, err)
+			}
+			if err := w.FlushMode(mode); err != nil {
+				t.Errorf("mode %d: flushing twice: %v", mode, err)
+			}
+			if (mode == SyncFlush || mode == FullFlush) && !bytes.HasSuffix(b.Bytes(), []byte{0, 0, 0xff, 0xff}) {
+				t.Errorf("mode %d: output is not byte aligned by an empty stored block", mode)
+			}
+		}
+		w.Close()
+
+		r, err := NewReader(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		act := &bytes.Buffer{}
+		if _, err := io.Copy(act, r); err != nil {
+			t.Errorf("mode %d: %v", mode, err)
+		}
+		r.Close()
+		sliceEquals(t, bytes.Repeat(longString, 3), act.Bytes())
+	}
+}
+
+func TestFlushMode_Invalid(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+	defer w.Close()
+
+	if err := w.FlushMode(4); err != errInvalidFlushMode {
+		t.Errorf("expected %v; got %v", errInvalidFlushMode, err)
+	}
+}
+
+func TestPrime(t *testing.T) {
+	b := &bytes.Buffer{}
+	w, err := NewRawWriter(b, DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Prime(3, 5); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(shortString)
+	w.Close()
+
+	if b.Bytes()[0]&7 != 5 {
+		t.Errorf("primed bits not in front of the stream: want %03b; got %03b", 5, b.Bytes()[0]&7)
+	}
+}
+
+func TestPrime_Invalid(t *testing.T) {
+	w, _ := NewRawWriter(nil, DefaultCompression)
+	defer w.Close()
+
+	for _, bits := range []int{0, 17} {
+		if err := w.Prime(bits, 0); err != errInvalidPrimeBits {
+			t.Errorf("expected %v; got %v", errInvalidPrimeBits, err)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	prefix := []byte(`{"version":1,"kind":"message","payload":`)
+	messages := [][]byte{[]byte(`"first"}`), []byte(`"second"}`), shortString}
+
+	head := &bytes.Buffer{}
+	w := NewWriter(head)
+	w.Write(prefix)
+
+	for _, msg := range messages {
+		tail := &bytes.Buffer{}
+		clone, err := w.Clone(tail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clone.Write(msg)
+		if err := clone.Close(); err != nil {
+			t.Error(err)
+		}
+
+		compressed := append(append([]byte{}, head.Bytes()...), tail.Bytes()...)
+		r, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		act := &bytes.Buffer{}
+		if _, err := io.Copy(act, r); err != nil {
+			t.Error(err)
+		}
+		sliceEquals(t, append(append([]byte{}, prefix...), msg...), act.Bytes())
+	}
+
+	// the original is unaffected by its clones
+	w.Write(shortString)
+	w.Close()
+	r, err := zlib.NewReader(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := &bytes.Buffer{}
+	if _, err := io.Copy(act, r); err != nil {
+		t.Error(err)
+	}
+	sliceEquals(t, append(append([]byte{}, prefix...), shortString...), act.Bytes())
+
+	if _, err := w.Clone(nil); err != errIsClosed {
+		t.Errorf("expected %v; got %v", errIsClosed, err)
+	}
+}